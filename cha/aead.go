@@ -0,0 +1,81 @@
+package cha
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"fmt"
+
+	"golang.org/x/crypto/chacha20poly1305"
+
+	"github.com/Lucifer07/Structo/errdefs"
+)
+
+// aesGCMVersion marks envelopes of NewAES256GCM, whose nonce is 12 bytes.
+const aesGCMVersion = 4
+
+// AEAD encrypts with an authenticated cipher into the envelope described
+// in encrypt.go. It implements structo.Encryptor and structo.EncryptorWithAD.
+type AEAD struct {
+	aead    cipher.AEAD
+	version byte
+}
+
+// NewChaCha20Poly1305 returns an AEAD using XChaCha20-Poly1305 with a
+// random nonce per message. Its envelopes are those of EncryptData, so
+// either decrypts the other's output given the same key.
+func NewChaCha20Poly1305(key []byte) (*AEAD, error) {
+	if len(key) != KeySize {
+		return nil, fmt.Errorf("%w: %d bytes, want %d", errdefs.ErrInvalidKeySize, len(key), KeySize)
+	}
+
+	aead, err := chacha20poly1305.NewX(key)
+	if err != nil {
+		return nil, err
+	}
+	return &AEAD{aead: aead, version: envelopeVersion}, nil
+}
+
+// NewAES256GCM returns an AEAD using AES-256-GCM with a random 12 byte
+// nonce per message, for deployments that require AES. With nonces of that
+// size a key should seal no more than 2^32 messages.
+func NewAES256GCM(key []byte) (*AEAD, error) {
+	if len(key) != KeySize {
+		return nil, fmt.Errorf("%w: %d bytes, want %d", errdefs.ErrInvalidKeySize, len(key), KeySize)
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &AEAD{aead: aead, version: aesGCMVersion}, nil
+}
+
+// Encrypt seals plaintext with a random nonce and returns the envelope.
+func (a *AEAD) Encrypt(plaintext []byte) ([]byte, error) {
+	return a.EncryptWithAD(plaintext, nil)
+}
+
+// EncryptWithAD is Encrypt binding the ciphertext to ad, see
+// EncryptData.EncryptBytesWithAD.
+func (a *AEAD) EncryptWithAD(plaintext, ad []byte) ([]byte, error) {
+	return seal(a.aead, []byte{a.version}, plaintext, ad)
+}
+
+// Decrypt opens an envelope written by Encrypt. Data that was tampered
+// with, sealed with another key or cipher or is no envelope at all fails
+// with errdefs.ErrDecryptionFailed.
+func (a *AEAD) Decrypt(ciphertext []byte) ([]byte, error) {
+	return a.DecryptWithAD(ciphertext, nil)
+}
+
+// DecryptWithAD opens an envelope written by EncryptWithAD with the same ad.
+func (a *AEAD) DecryptWithAD(ciphertext, ad []byte) ([]byte, error) {
+	if len(ciphertext) == 0 || ciphertext[0] != a.version {
+		return nil, fmt.Errorf("%w: no version %d envelope", errdefs.ErrDecryptionFailed, a.version)
+	}
+	return open(a.aead, ciphertext[:1], ciphertext[1:], ad)
+}
@@ -1,59 +1,154 @@
 package cha
 
 import (
+	"crypto/cipher"
+	"crypto/rand"
 	"encoding/base64"
+	"fmt"
 
-	"github.com/google/uuid"
-	"golang.org/x/crypto/chacha20"
+	"golang.org/x/crypto/chacha20poly1305"
+
+	"github.com/Lucifer07/Structo/errdefs"
+)
+
+// KeySize is the length of the keys accepted by NewEncryptorWithKey.
+const KeySize = chacha20poly1305.KeySize
+
+// Ciphertexts are written in a versioned envelope:
+//
+//	version uint8     envelopeVersion, keyringVersion or aesGCMVersion
+//	key id  uint32    little-endian, keyringVersion only, see Keyring
+//	nonce   [24]byte  random XChaCha20 nonce, 12 bytes for AES-GCM
+//	sealed  []byte    ciphertext and tag of XChaCha20-Poly1305 or AES-GCM
+//
+// Everything before the nonce is authenticated as additional data, followed
+// by the caller's associated data of the WithAD methods, which is not
+// stored. Version 1 was the unauthenticated format of earlier releases and is
+// not read.
+const (
+	envelopeVersion = 2
+	keyringVersion  = 3
 )
 
 type EncryptData struct {
-	nonce []byte
-	key   []byte
+	aead cipher.AEAD
 }
 
+// NewEncryptor returns an EncryptData with a random key. Only the same
+// instance can decrypt its output, use NewEncryptorWithKey for data that is
+// stored or shared. It panics if the system's secure random source fails,
+// as there is no safe fallback key.
 func NewEncryptor() *EncryptData {
-	nonce := make([]byte, chacha20.NonceSize)
-	key, _ := uuid.NewV7()
+	key := make([]byte, KeySize)
+	if _, err := rand.Read(key); err != nil {
+		panic(fmt.Sprintf("cha: reading a random key: %v", err))
+	}
 
-	return &EncryptData{
-		key:   []byte(key.String()[0:8] + key.String()[9:13] + key.String()[14:18] + key.String()[19:23] + key.String()[24:]),
-		nonce: nonce,
+	e, err := NewEncryptorWithKey(key)
+	if err != nil {
+		panic(err)
 	}
+	return e
 }
 
-func (e *EncryptData) Encrypt(plaintextBytes []byte) (string, error) {
+// NewEncryptorWithKey returns an EncryptData using key, which must be
+// KeySize bytes long. Instances with the same key decrypt each other's
+// output.
+func NewEncryptorWithKey(key []byte) (*EncryptData, error) {
+	if len(key) != KeySize {
+		return nil, fmt.Errorf("%w: %d bytes, want %d", errdefs.ErrInvalidKeySize, len(key), KeySize)
+	}
 
-	cipher, err := chacha20.NewUnauthenticatedCipher(e.key, e.nonce)
+	aead, err := chacha20poly1305.NewX(key)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
+	return &EncryptData{aead: aead}, nil
+}
 
-	ciphertext := make([]byte, len(plaintextBytes))
-	cipher.XORKeyStream(ciphertext, plaintextBytes)
+// Encrypt encrypts plaintextBytes and encodes the ciphertext as standard Base64.
+func (e *EncryptData) Encrypt(plaintextBytes []byte) (string, error) {
+	ciphertext, err := e.EncryptBytes(plaintextBytes)
+	if err != nil {
+		return "", err
+	}
 
 	// Encode ciphertext to Base64
-	encodedCiphertext := base64.StdEncoding.EncodeToString(ciphertext)
-
-	return encodedCiphertext, nil
+	return base64.StdEncoding.EncodeToString(ciphertext), nil
 }
 
-// Decrypt using ChaCha20
+// Decrypt decodes a standard Base64 string produced by Encrypt and decrypts it.
 func (e *EncryptData) Decrypt(encodedCiphertext string) (string, error) {
-
-	cipher, err := chacha20.NewUnauthenticatedCipher(e.key, e.nonce)
+	// Decode Base64 ciphertext
+	ciphertext, err := base64.StdEncoding.DecodeString(encodedCiphertext)
 	if err != nil {
 		return "", err
 	}
 
-	// Decode Base64 ciphertext
-	ciphertext, err := base64.StdEncoding.DecodeString(encodedCiphertext)
+	plaintext, err := e.DecryptBytes(ciphertext)
 	if err != nil {
 		return "", err
 	}
+	return string(plaintext), nil
+}
+
+// EncryptBytes encrypts using XChaCha20-Poly1305 with a random nonce and
+// returns the envelope.
+func (e *EncryptData) EncryptBytes(plaintext []byte) ([]byte, error) {
+	return e.EncryptBytesWithAD(plaintext, nil)
+}
+
+// EncryptBytesWithAD is EncryptBytes binding the ciphertext to ad, such as
+// a record id: only DecryptBytesWithAD with the same ad can open it.
+func (e *EncryptData) EncryptBytesWithAD(plaintext, ad []byte) ([]byte, error) {
+	return seal(e.aead, []byte{envelopeVersion}, plaintext, ad)
+}
+
+// DecryptBytes opens an envelope written by EncryptBytes. Data that was
+// tampered with, encrypted with another key or is no envelope at all fails
+// with errdefs.ErrDecryptionFailed.
+func (e *EncryptData) DecryptBytes(ciphertext []byte) ([]byte, error) {
+	return e.DecryptBytesWithAD(ciphertext, nil)
+}
+
+// DecryptBytesWithAD opens an envelope written by EncryptBytesWithAD. A
+// different ad fails with errdefs.ErrDecryptionFailed.
+func (e *EncryptData) DecryptBytesWithAD(ciphertext, ad []byte) ([]byte, error) {
+	if len(ciphertext) == 0 || ciphertext[0] != envelopeVersion {
+		return nil, fmt.Errorf("%w: no version %d envelope", errdefs.ErrDecryptionFailed, envelopeVersion)
+	}
+	return open(e.aead, ciphertext[:1], ciphertext[1:], ad)
+}
 
-	plaintext := make([]byte, len(ciphertext))
-	cipher.XORKeyStream(plaintext, ciphertext)
+// seal returns header followed by a random nonce and the sealed plaintext,
+// authenticating header and ad as additional data.
+func seal(aead cipher.AEAD, header, plaintext, ad []byte) ([]byte, error) {
+	out := make([]byte, len(header)+aead.NonceSize(), len(header)+aead.NonceSize()+len(plaintext)+aead.Overhead())
+	copy(out, header)
+	nonce := out[len(header):]
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
 
-	return string(plaintext), nil
+	return aead.Seal(out, nonce, plaintext, additionalData(header, ad)), nil
+}
+
+// open reverses seal, body is the envelope after header.
+func open(aead cipher.AEAD, header, body, ad []byte) ([]byte, error) {
+	if len(body) < aead.NonceSize()+aead.Overhead() {
+		return nil, fmt.Errorf("%w: %d bytes", errdefs.ErrDecryptionFailed, len(header)+len(body))
+	}
+
+	plaintext, err := aead.Open(nil, body[:aead.NonceSize()], body[aead.NonceSize():], additionalData(header, ad))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errdefs.ErrDecryptionFailed, err)
+	}
+	return plaintext, nil
+}
+
+func additionalData(header, ad []byte) []byte {
+	if len(ad) == 0 {
+		return header
+	}
+	return append(header[:len(header):len(header)], ad...)
 }
@@ -0,0 +1,170 @@
+package cha
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/Lucifer07/Structo/errdefs"
+)
+
+func testKey(b byte) []byte {
+	return bytes.Repeat([]byte{b}, KeySize)
+}
+
+// cipherSuite is any of the encryptors of this package.
+type cipherSuite interface {
+	EncryptWithAD(plaintext, ad []byte) ([]byte, error)
+	DecryptWithAD(ciphertext, ad []byte) ([]byte, error)
+}
+
+// encryptData gives EncryptData the method names of AEAD and Keyring.
+type encryptData struct{ *EncryptData }
+
+func (e encryptData) EncryptWithAD(plaintext, ad []byte) ([]byte, error) {
+	return e.EncryptBytesWithAD(plaintext, ad)
+}
+
+func (e encryptData) DecryptWithAD(ciphertext, ad []byte) ([]byte, error) {
+	return e.DecryptBytesWithAD(ciphertext, ad)
+}
+
+func testSuites(t *testing.T) map[string]cipherSuite {
+	t.Helper()
+	data, err := NewEncryptorWithKey(testKey(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	chacha, err := NewChaCha20Poly1305(testKey(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	gcm, err := NewAES256GCM(testKey(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyring := NewKeyring()
+	if err := keyring.Add(7, testKey(1)); err != nil {
+		t.Fatal(err)
+	}
+	return map[string]cipherSuite{
+		"EncryptData":      encryptData{data},
+		"ChaCha20Poly1305": chacha,
+		"AES256GCM":        gcm,
+		"Keyring":          keyring,
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	plaintext := []byte("structo")
+	for name, suite := range testSuites(t) {
+		t.Run(name, func(t *testing.T) {
+			for _, ad := range [][]byte{nil, []byte("record-1")} {
+				sealed, err := suite.EncryptWithAD(plaintext, ad)
+				if err != nil {
+					t.Fatal(err)
+				}
+				again, err := suite.EncryptWithAD(plaintext, ad)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if bytes.Equal(sealed, again) {
+					t.Error("equal plaintexts encrypt to equal ciphertexts")
+				}
+
+				got, err := suite.DecryptWithAD(sealed, ad)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !bytes.Equal(got, plaintext) {
+					t.Errorf("decrypted %q, want %q", got, plaintext)
+				}
+			}
+		})
+	}
+}
+
+// Flipping any byte of the envelope, header, nonce, ciphertext or tag, and
+// opening with other associated data must fail.
+func TestTamperRejected(t *testing.T) {
+	for name, suite := range testSuites(t) {
+		t.Run(name, func(t *testing.T) {
+			ad := []byte("record-1")
+			sealed, err := suite.EncryptWithAD([]byte("structo"), ad)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for i := range sealed {
+				tampered := append([]byte(nil), sealed...)
+				tampered[i] ^= 0x01
+				if _, err := suite.DecryptWithAD(tampered, ad); err == nil {
+					t.Errorf("byte %d flipped: decrypted without error", i)
+				}
+			}
+			for _, short := range [][]byte{nil, sealed[:1], sealed[:len(sealed)-1]} {
+				if _, err := suite.DecryptWithAD(short, ad); err == nil {
+					t.Errorf("%d of %d bytes: decrypted without error", len(short), len(sealed))
+				}
+			}
+
+			for _, other := range [][]byte{nil, []byte("record-2")} {
+				if _, err := suite.DecryptWithAD(sealed, other); !errors.Is(err, errdefs.ErrDecryptionFailed) {
+					t.Errorf("ad %q: %v, want ErrDecryptionFailed", other, err)
+				}
+			}
+			withoutAD, err := suite.EncryptWithAD([]byte("structo"), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := suite.DecryptWithAD(withoutAD, ad); !errors.Is(err, errdefs.ErrDecryptionFailed) {
+				t.Errorf("ad given for data sealed without: %v, want ErrDecryptionFailed", err)
+			}
+		})
+	}
+}
+
+func TestWrongKeyRejected(t *testing.T) {
+	e, err := NewEncryptorWithKey(testKey(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := NewEncryptorWithKey(testKey(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sealed, err := e.EncryptBytes([]byte("structo"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := other.DecryptBytes(sealed); !errors.Is(err, errdefs.ErrDecryptionFailed) {
+		t.Errorf("other key: %v, want ErrDecryptionFailed", err)
+	}
+
+	// the envelope version keeps one cipher from reading the other's data
+	gcm, err := NewAES256GCM(testKey(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := gcm.Decrypt(sealed); !errors.Is(err, errdefs.ErrDecryptionFailed) {
+		t.Errorf("AES-GCM opening XChaCha20-Poly1305: %v, want ErrDecryptionFailed", err)
+	}
+}
+
+func TestInvalidKeySize(t *testing.T) {
+	for _, size := range []int{0, 16, KeySize - 1, KeySize + 1} {
+		key := make([]byte, size)
+		if _, err := NewEncryptorWithKey(key); !errors.Is(err, errdefs.ErrInvalidKeySize) {
+			t.Errorf("NewEncryptorWithKey, %d bytes: %v, want ErrInvalidKeySize", size, err)
+		}
+		if _, err := NewChaCha20Poly1305(key); !errors.Is(err, errdefs.ErrInvalidKeySize) {
+			t.Errorf("NewChaCha20Poly1305, %d bytes: %v, want ErrInvalidKeySize", size, err)
+		}
+		if _, err := NewAES256GCM(key); !errors.Is(err, errdefs.ErrInvalidKeySize) {
+			t.Errorf("NewAES256GCM, %d bytes: %v, want ErrInvalidKeySize", size, err)
+		}
+		if err := NewKeyring().Add(1, key); !errors.Is(err, errdefs.ErrInvalidKeySize) {
+			t.Errorf("Keyring.Add, %d bytes: %v, want ErrInvalidKeySize", size, err)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package cha
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sync"
+
+	"github.com/Lucifer07/Structo/errdefs"
+)
+
+// Keyring holds several keys by id so they can be rotated without losing
+// access to old data. Ciphertexts record the id of the key that sealed
+// them, Encrypt always uses the active key and Decrypt the one
+// the ciphertext names. A Keyring is safe for concurrent use.
+type Keyring struct {
+	mu        sync.RWMutex
+	keys      map[uint32]*EncryptData
+	active    uint32
+	hasActive bool
+}
+
+// NewKeyring returns an empty Keyring, see Add.
+func NewKeyring() *Keyring {
+	return &Keyring{keys: make(map[uint32]*EncryptData)}
+}
+
+// Add adds key under id. The first key added becomes the active one, later
+// ones only after SetActive. An id cannot be reused, since data sealed with
+// the previous key would become unreadable.
+func (k *Keyring) Add(id uint32, key []byte) error {
+	e, err := NewEncryptorWithKey(key)
+	if err != nil {
+		return err
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if _, ok := k.keys[id]; ok {
+		return fmt.Errorf("%w: %d", errdefs.ErrDuplicateKeyID, id)
+	}
+	k.keys[id] = e
+	if !k.hasActive {
+		k.active, k.hasActive = id, true
+	}
+	return nil
+}
+
+// SetActive makes the key added under id the one Encrypt uses.
+func (k *Keyring) SetActive(id uint32) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if _, ok := k.keys[id]; !ok {
+		return fmt.Errorf("%w: %d", errdefs.ErrUnknownKeyID, id)
+	}
+	k.active, k.hasActive = id, true
+	return nil
+}
+
+// Active returns the id of the active key, false if the Keyring is empty.
+func (k *Keyring) Active() (uint32, bool) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	return k.active, k.hasActive
+}
+
+// Encrypt encrypts with the active key and returns an envelope
+// recording its id.
+func (k *Keyring) Encrypt(plaintext []byte) ([]byte, error) {
+	return k.EncryptWithAD(plaintext, nil)
+}
+
+// EncryptWithAD is Encrypt binding the ciphertext to ad, see
+// EncryptData.EncryptBytesWithAD.
+func (k *Keyring) EncryptWithAD(plaintext, ad []byte) ([]byte, error) {
+	k.mu.RLock()
+	e, id, ok := k.keys[k.active], k.active, k.hasActive
+	k.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w: keyring has no active key", errdefs.ErrUnknownKeyID)
+	}
+
+	header := binary.LittleEndian.AppendUint32([]byte{keyringVersion}, id)
+	return seal(e.aead, header, plaintext, ad)
+}
+
+// Decrypt opens an envelope written by Encrypt with the key it
+// names, failing with errdefs.ErrUnknownKeyID if that key is not in the
+// Keyring. Envelopes of a single EncryptData carry no id and are tried with
+// every key, the active one first.
+func (k *Keyring) Decrypt(ciphertext []byte) ([]byte, error) {
+	return k.DecryptWithAD(ciphertext, nil)
+}
+
+// DecryptWithAD opens an envelope written by EncryptWithAD with
+// the same ad.
+func (k *Keyring) DecryptWithAD(ciphertext, ad []byte) ([]byte, error) {
+	if len(ciphertext) > 0 && ciphertext[0] == envelopeVersion {
+		return k.decryptUnnamed(ciphertext, ad)
+	}
+	if len(ciphertext) < 5 || ciphertext[0] != keyringVersion {
+		return nil, fmt.Errorf("%w: no keyring envelope", errdefs.ErrDecryptionFailed)
+	}
+
+	id := binary.LittleEndian.Uint32(ciphertext[1:5])
+	k.mu.RLock()
+	e, ok := k.keys[id]
+	k.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w: %d", errdefs.ErrUnknownKeyID, id)
+	}
+	return open(e.aead, ciphertext[:5], ciphertext[5:], ad)
+}
+
+func (k *Keyring) decryptUnnamed(ciphertext, ad []byte) ([]byte, error) {
+	k.mu.RLock()
+	candidates := make([]*EncryptData, 0, len(k.keys))
+	if k.hasActive {
+		candidates = append(candidates, k.keys[k.active])
+	}
+	for id, e := range k.keys {
+		if id != k.active {
+			candidates = append(candidates, e)
+		}
+	}
+	k.mu.RUnlock()
+
+	err := fmt.Errorf("%w: keyring is empty", errdefs.ErrDecryptionFailed)
+	for _, e := range candidates {
+		var plaintext []byte
+		if plaintext, err = e.DecryptBytesWithAD(ciphertext, ad); err == nil {
+			return plaintext, nil
+		}
+	}
+	return nil, err
+}
@@ -0,0 +1,95 @@
+package cha
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"testing"
+
+	"github.com/Lucifer07/Structo/errdefs"
+)
+
+// After a rotation old envelopes still open with the key they name, new
+// ones use the active key.
+func TestKeyringRotation(t *testing.T) {
+	keyring := NewKeyring()
+	if err := keyring.Add(1, testKey(1)); err != nil {
+		t.Fatal(err)
+	}
+	old, err := keyring.Encrypt([]byte("old"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := keyring.Add(2, testKey(2)); err != nil {
+		t.Fatal(err)
+	}
+	if id, _ := keyring.Active(); id != 1 {
+		t.Fatalf("active key %d after Add, want 1", id)
+	}
+	if err := keyring.SetActive(2); err != nil {
+		t.Fatal(err)
+	}
+	sealed, err := keyring.Encrypt([]byte("new"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id := binary.LittleEndian.Uint32(sealed[1:5]); id != 2 {
+		t.Errorf("sealed with key %d, want 2", id)
+	}
+
+	for want, envelope := range map[string][]byte{"old": old, "new": sealed} {
+		got, err := keyring.Decrypt(envelope)
+		if err != nil {
+			t.Fatalf("%s: %v", want, err)
+		}
+		if !bytes.Equal(got, []byte(want)) {
+			t.Errorf("decrypted %q, want %q", got, want)
+		}
+	}
+
+	// envelopes of a single EncryptData carry no id and are tried with
+	// every key
+	e, err := NewEncryptorWithKey(testKey(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	unnamed, err := e.EncryptBytes([]byte("unnamed"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := keyring.Decrypt(unnamed); err != nil || string(got) != "unnamed" {
+		t.Errorf("unnamed envelope: %q, %v", got, err)
+	}
+}
+
+func TestKeyringUnknownKey(t *testing.T) {
+	keyring := NewKeyring()
+	if _, err := keyring.Encrypt([]byte("x")); !errors.Is(err, errdefs.ErrUnknownKeyID) {
+		t.Errorf("Encrypt with an empty keyring: %v, want ErrUnknownKeyID", err)
+	}
+	if err := keyring.SetActive(3); !errors.Is(err, errdefs.ErrUnknownKeyID) {
+		t.Errorf("SetActive(3): %v, want ErrUnknownKeyID", err)
+	}
+
+	if err := keyring.Add(1, testKey(1)); err != nil {
+		t.Fatal(err)
+	}
+	if err := keyring.Add(1, testKey(2)); !errors.Is(err, errdefs.ErrDuplicateKeyID) {
+		t.Errorf("Add of a used id: %v, want ErrDuplicateKeyID", err)
+	}
+	sealed, err := keyring.Encrypt([]byte("x"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the same envelope, naming a key the keyring does not have
+	other := append([]byte(nil), sealed...)
+	binary.LittleEndian.PutUint32(other[1:5], 9)
+	if _, err := keyring.Decrypt(other); !errors.Is(err, errdefs.ErrUnknownKeyID) {
+		t.Errorf("Decrypt naming key 9: %v, want ErrUnknownKeyID", err)
+	}
+	if _, err := NewKeyring().Decrypt(sealed); !errors.Is(err, errdefs.ErrUnknownKeyID) {
+		t.Errorf("Decrypt with another keyring: %v, want ErrUnknownKeyID", err)
+	}
+}
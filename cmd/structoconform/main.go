@@ -0,0 +1,181 @@
+// Command structoconform maintains the conformance vectors of the structo
+// binary format in testdata/conformance, see docs/binary-format.md.
+//
+// Usage:
+//
+//	go run ./cmd/structoconform         # verify the vectors
+//	go run ./cmd/structoconform -write  # regenerate them
+//
+// Every vector is a value of one of the types in internal/conformance,
+// encoded with a set of Converter options. Verification checks that encoding the value
+// reproduces the stored blob byte for byte, and that decoding the blob and
+// encoding the result again does as well.
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"reflect"
+
+	structo "github.com/Lucifer07/Structo"
+	"github.com/Lucifer07/Structo/internal/conformance"
+)
+
+var (
+	dir   = flag.String("dir", filepath.Join("testdata", "conformance"), "directory of the vectors")
+	write = flag.Bool("write", false, "regenerate the vectors instead of verifying them")
+)
+
+var options = map[string]structo.ConverterOption{
+	"tagged":     structo.WithTaggedFields(),
+	"compact":    structo.WithCompactEncoding(),
+	"references": structo.WithReferences(),
+	"exported":   structo.WithExportedOnly(),
+	"canonical":  structo.WithCanonical(),
+}
+
+// indexEntry describes a vector in vectors.json.
+type indexEntry struct {
+	Name    string          `json:"name"`
+	File    string          `json:"file"`
+	Options []string        `json:"options"`
+	Type    string          `json:"type"`
+	Value   json.RawMessage `json:"value,omitempty"`
+	Hex     string          `json:"hex"`
+}
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("structoconform: ")
+	flag.Parse()
+
+	var index []indexEntry
+	failed := false
+	for _, vec := range conformance.Vectors() {
+		entry, blob, err := encode(vec)
+		if err != nil {
+			log.Fatalf("%s: %v", vec.Name, err)
+		}
+		index = append(index, entry)
+
+		path := filepath.Join(*dir, entry.File)
+		if *write {
+			if err := os.WriteFile(path, blob, 0o644); err != nil {
+				log.Fatal(err)
+			}
+			continue
+		}
+		if err := verify(vec, blob, path); err != nil {
+			log.Printf("%s: %v", vec.Name, err)
+			failed = true
+		}
+	}
+
+	src, err := marshalIndex(index)
+	if err != nil {
+		log.Fatal(err)
+	}
+	indexPath := filepath.Join(*dir, "vectors.json")
+	if *write {
+		if err := os.WriteFile(indexPath, src, 0o644); err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("wrote %d vectors to %s\n", len(index), *dir)
+		return
+	}
+	if stored, err := os.ReadFile(indexPath); err != nil || !bytes.Equal(stored, src) {
+		log.Printf("%s is out of date, run with -write", indexPath)
+		failed = true
+	}
+	if failed {
+		os.Exit(1)
+	}
+	fmt.Printf("%d vectors ok\n", len(index))
+}
+
+// marshalIndex returns the content of vectors.json.
+func marshalIndex(index []indexEntry) ([]byte, error) {
+	src, err := json.MarshalIndent(index, "", "\t")
+	if err != nil {
+		return nil, err
+	}
+	return append(src, '\n'), nil
+}
+
+// encode encodes the value of vec and describes it for the index.
+func encode(vec conformance.Vector) (indexEntry, []byte, error) {
+	blob, err := converter(vec).StructToBinary(vec.Value)
+	if err != nil {
+		return indexEntry{}, nil, err
+	}
+
+	entry := indexEntry{
+		Name:    vec.Name,
+		File:    vec.Name + ".bin",
+		Options: vec.Options,
+		Type:    typeName(reflect.Indirect(reflect.ValueOf(vec.Value)).Type()),
+		Hex:     hex.EncodeToString(blob),
+	}
+	if entry.Options == nil {
+		entry.Options = []string{}
+	}
+	if !vec.Cyclic {
+		if entry.Value, err = json.Marshal(vec.Value); err != nil {
+			return indexEntry{}, nil, err
+		}
+	}
+	return entry, blob, nil
+}
+
+// typeName returns the import path and name of t, as hashed into the
+// fingerprint of the tagged layout.
+func typeName(t reflect.Type) string {
+	return t.PkgPath() + "." + t.Name()
+}
+
+// verify compares the stored blob at path with blob, the fresh encoding of
+// vec, then decodes it and checks that the result encodes the same again.
+func verify(vec conformance.Vector, blob []byte, path string) error {
+	stored, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(stored, blob) {
+		return fmt.Errorf("encoding changed:\n\tstored  %x\n\tencoded %x", stored, blob)
+	}
+
+	conv := converter(vec)
+	res := reflect.New(reflect.TypeOf(vec.Value))
+	if err := conv.BinaryToStruct(stored, res.Interface()); err != nil {
+		return fmt.Errorf("decoding: %w", err)
+	}
+	if !reflect.DeepEqual(res.Elem().Interface(), vec.Value) {
+		return fmt.Errorf("decoded %+v, want %+v", res.Elem().Interface(), vec.Value)
+	}
+	again, err := conv.StructToBinary(res.Elem().Interface())
+	if err != nil {
+		return fmt.Errorf("encoding the decoded value: %w", err)
+	}
+	if !bytes.Equal(again, stored) {
+		return fmt.Errorf("decoded value encodes differently:\n\tstored  %x\n\tencoded %x", stored, again)
+	}
+	return nil
+}
+
+func converter(vec conformance.Vector) structo.Converter {
+	var opts []structo.ConverterOption
+	for _, name := range vec.Options {
+		opt, ok := options[name]
+		if !ok {
+			log.Fatalf("%s: unknown option %q", vec.Name, name)
+		}
+		opts = append(opts, opt)
+	}
+	return structo.NewConverter(opts...)
+}
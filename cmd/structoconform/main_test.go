@@ -0,0 +1,40 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Lucifer07/Structo/internal/conformance"
+)
+
+// TestVectors runs the verification of the command over the committed
+// vectors, so format changes that break them fail go test.
+func TestVectors(t *testing.T) {
+	vecDir := filepath.Join("..", "..", "testdata", "conformance")
+
+	var index []indexEntry
+	for _, vec := range conformance.Vectors() {
+		entry, blob, err := encode(vec)
+		if err != nil {
+			t.Fatalf("%s: %v", vec.Name, err)
+		}
+		index = append(index, entry)
+		if err := verify(vec, blob, filepath.Join(vecDir, entry.File)); err != nil {
+			t.Errorf("%s: %v", vec.Name, err)
+		}
+	}
+
+	src, err := marshalIndex(index)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stored, err := os.ReadFile(filepath.Join(vecDir, "vectors.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(stored, src) {
+		t.Error("vectors.json is out of date, run go run ./cmd/structoconform -write")
+	}
+}
@@ -0,0 +1,484 @@
+// Command structogen generates reflection free MarshalStructo and
+// UnmarshalStructo methods for struct types, byte compatible with the
+// default layout of the structo binary Converter. The Converter detects the
+// generated methods and prefers them over reflection.
+//
+// Usage:
+//
+//	//go:generate go run github.com/Lucifer07/Structo/cmd/structogen -type=User,Address
+//
+// Fields of basic types, strings, slices of those and other generated types
+// are encoded inline; any other field falls back to structo.AppendField and
+// structo.ReadField. The `structo:"-"` and `structo:",omitempty"` tags are
+// honored like the Converter does.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"log"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+
+	structo "github.com/Lucifer07/Structo"
+)
+
+var (
+	typeNames = flag.String("type", "", "comma-separated list of struct type names; must be set")
+	output    = flag.String("output", "", "output file name; default <dir>/<type>_structo.go")
+)
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("structogen: ")
+	flag.Parse()
+	if *typeNames == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	dir := "."
+	if flag.NArg() > 0 {
+		dir = flag.Arg(0)
+	}
+
+	types := strings.Split(*typeNames, ",")
+	src, err := generateFile(dir, types)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	outputName := *output
+	if outputName == "" {
+		outputName = filepath.Join(dir, strings.ToLower(types[0])+"_structo.go")
+	}
+	if err := os.WriteFile(outputName, src, 0o644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// generateFile returns the source of the methods for types, struct types
+// declared in the package in dir.
+func generateFile(dir string, types []string) ([]byte, error) {
+	pkg, structs, err := parsePackage(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	g := &generator{structs: structs, generated: map[string]bool{}, imports: map[string]bool{}}
+	for _, name := range types {
+		g.generated[name] = true
+	}
+	for _, name := range types {
+		if err := g.generate(name); err != nil {
+			return nil, err
+		}
+	}
+	return g.source(pkg)
+}
+
+// parsePackage returns the package name and the struct types declared in dir.
+func parsePackage(dir string) (string, map[string]*ast.TypeSpec, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		return "", nil, err
+	}
+
+	var pkg string
+	structs := map[string]*ast.TypeSpec{}
+	fset := token.NewFileSet()
+	for _, file := range files {
+		if strings.HasSuffix(file, "_test.go") {
+			continue
+		}
+		f, err := parser.ParseFile(fset, file, nil, parser.SkipObjectResolution)
+		if err != nil {
+			return "", nil, err
+		}
+		pkg = f.Name.Name
+		ast.Inspect(f, func(n ast.Node) bool {
+			if spec, ok := n.(*ast.TypeSpec); ok {
+				if _, ok := spec.Type.(*ast.StructType); ok {
+					structs[spec.Name.Name] = spec
+				}
+			}
+			return true
+		})
+	}
+	if pkg == "" {
+		return "", nil, fmt.Errorf("no Go files in %s", dir)
+	}
+	return pkg, structs, nil
+}
+
+type generator struct {
+	buf       bytes.Buffer
+	structs   map[string]*ast.TypeSpec
+	generated map[string]bool
+	imports   map[string]bool
+}
+
+// use records an import of the generated file.
+func (g *generator) use(paths ...string) {
+	for _, path := range paths {
+		g.imports[path] = true
+	}
+}
+
+func (g *generator) printf(format string, args ...interface{}) {
+	fmt.Fprintf(&g.buf, format, args...)
+}
+
+// field is a struct field in declaration order, the order the Converter
+// encodes them in.
+type field struct {
+	name      string
+	typ       ast.Expr
+	omitEmpty bool
+}
+
+func (g *generator) fields(name string) ([]field, error) {
+	spec, ok := g.structs[name]
+	if !ok {
+		return nil, fmt.Errorf("struct type %s not found", name)
+	}
+	if spec.TypeParams != nil {
+		return nil, fmt.Errorf("generic type %s is not supported", name)
+	}
+
+	var fields []field
+	for _, f := range spec.Type.(*ast.StructType).Fields.List {
+		skip, omitEmpty, err := parseTag(f.Tag)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		if skip {
+			continue
+		}
+		if len(f.Names) == 0 {
+			fields = append(fields, field{name: embeddedName(f.Type), typ: f.Type, omitEmpty: omitEmpty})
+			continue
+		}
+		for _, n := range f.Names {
+			if n.Name == "_" {
+				return nil, fmt.Errorf("%s: blank fields are not supported", name)
+			}
+			fields = append(fields, field{name: n.Name, typ: f.Type, omitEmpty: omitEmpty})
+		}
+	}
+	return fields, nil
+}
+
+// parseTag reads the `structo` tag the way the Converter does. Names and
+// ids only matter to the tagged layout, which generated code does not cover.
+func parseTag(lit *ast.BasicLit) (skip, omitEmpty bool, err error) {
+	if lit == nil {
+		return false, false, nil
+	}
+	raw, err := strconv.Unquote(lit.Value)
+	if err != nil {
+		return false, false, err
+	}
+	tag := reflect.StructTag(raw).Get("structo")
+	if tag == "-" {
+		return true, false, nil
+	}
+	for i, t := range strings.Split(tag, ",") {
+		switch {
+		case i == 0 && !strings.Contains(t, "="):
+		case t == "omitempty":
+			omitEmpty = true
+		case strings.HasPrefix(t, "id="):
+		default:
+			return false, false, fmt.Errorf("invalid structo tag option %q", t)
+		}
+	}
+	return false, omitEmpty, nil
+}
+
+func embeddedName(expr ast.Expr) string {
+	switch t := expr.(type) {
+	case *ast.StarExpr:
+		return embeddedName(t.X)
+	case *ast.SelectorExpr:
+		return t.Sel.Name
+	case *ast.Ident:
+		return t.Name
+	}
+	return ""
+}
+
+func (g *generator) generate(name string) error {
+	fields, err := g.fields(name)
+	if err != nil {
+		return err
+	}
+
+	g.printf("// StructoFormatVersion reports the binary format the methods of %s were generated for.\n", name)
+	g.printf("func (*%s) StructoFormatVersion() int { return %d }\n\n", name, structo.FormatVersion)
+
+	g.printf("// MarshalStructo appends the binary encoding of x to b.\n")
+	g.printf("func (x *%s) MarshalStructo(b []byte) ([]byte, error) {\n", name)
+	if g.needsErr(fields) {
+		g.printf("var err error\n")
+	}
+	for _, f := range fields {
+		if f.omitEmpty {
+			g.printf("if %s {\nb = append(b, 0)\n} else {\nb = append(b, 1)\n", g.isZero("x."+f.name, f.typ))
+			g.marshalField("x."+f.name, f.typ)
+			g.printf("}\n")
+			continue
+		}
+		g.marshalField("x."+f.name, f.typ)
+	}
+	g.printf("return b, nil\n}\n\n")
+
+	g.printf("// UnmarshalStructo decodes x from the start of data and returns the number of bytes read.\n")
+	g.printf("func (x *%s) UnmarshalStructo(data []byte) (int, error) {\n", name)
+	g.printf("var n int\n")
+	for _, f := range fields {
+		if f.omitEmpty {
+			g.need("1")
+			g.use(`"github.com/Lucifer07/Structo/errdefs"`)
+			g.printf("if data[n] > 1 {\nreturn 0, errdefs.ErrInvalidTypeData\n}\n")
+			g.printf("n++\nif data[n-1] == 0 {\n")
+			g.setZero("x."+f.name, f.typ)
+			g.printf("} else {\n")
+			g.unmarshalField("x."+f.name, f.typ)
+			g.printf("}\n")
+			continue
+		}
+		g.unmarshalField("x."+f.name, f.typ)
+	}
+	g.printf("return n, nil\n}\n\n")
+	return nil
+}
+
+func (g *generator) needsErr(fields []field) bool {
+	for _, f := range fields {
+		if g.kind(f.typ) == kindNested || g.kind(f.typ) == kindFallback {
+			return true
+		}
+	}
+	return false
+}
+
+// isZero returns the condition under which an omitempty field is left out,
+// matching reflect.Value.IsZero.
+func (g *generator) isZero(expr string, typ ast.Expr) string {
+	switch g.kind(typ) {
+	case kindBool:
+		return "!" + expr
+	case kindInt, kindUint, kindFloat:
+		return expr + " == 0"
+	case kindString:
+		return expr + ` == ""`
+	case kindSlice, kindBytes:
+		return expr + " == nil"
+	}
+	g.use(`"reflect"`)
+	return "reflect.ValueOf(&" + expr + ").Elem().IsZero()"
+}
+
+// setZero resets an omitempty field that was left out.
+func (g *generator) setZero(expr string, typ ast.Expr) {
+	switch g.kind(typ) {
+	case kindBool:
+		g.printf("%s = false\n", expr)
+	case kindInt, kindUint, kindFloat:
+		g.printf("%s = 0\n", expr)
+	case kindString:
+		g.printf("%s = \"\"\n", expr)
+	case kindSlice, kindBytes:
+		g.printf("%s = nil\n", expr)
+	default:
+		g.use(`"reflect"`)
+		g.printf("reflect.ValueOf(&%s).Elem().SetZero()\n", expr)
+	}
+}
+
+type fieldKind int
+
+const (
+	kindFallback fieldKind = iota
+	kindBool
+	kindInt
+	kindUint
+	kindFloat
+	kindString
+	kindSlice
+	kindBytes
+	kindNested
+)
+
+var basicKinds = map[string]fieldKind{
+	"bool":    kindBool,
+	"int":     kindInt,
+	"int8":    kindInt,
+	"int16":   kindInt,
+	"int32":   kindInt,
+	"int64":   kindInt,
+	"rune":    kindInt,
+	"uint":    kindUint,
+	"uint8":   kindUint,
+	"uint16":  kindUint,
+	"uint32":  kindUint,
+	"uint64":  kindUint,
+	"byte":    kindUint,
+	"float32": kindFloat,
+	"float64": kindFloat,
+	"string":  kindString,
+}
+
+func (g *generator) kind(expr ast.Expr) fieldKind {
+	switch t := expr.(type) {
+	case *ast.Ident:
+		if k, ok := basicKinds[t.Name]; ok {
+			return k
+		}
+		if g.generated[t.Name] {
+			return kindNested
+		}
+	case *ast.ArrayType:
+		if t.Len == nil {
+			if ident, ok := t.Elt.(*ast.Ident); ok && basicKinds[ident.Name] != kindFallback {
+				if ident.Name == "byte" || ident.Name == "uint8" {
+					return kindBytes
+				}
+				return kindSlice
+			}
+		}
+	}
+	return kindFallback
+}
+
+func (g *generator) marshalField(expr string, typ ast.Expr) {
+	switch g.kind(typ) {
+	case kindBool:
+		g.printf("if %s {\nb = append(b, 1)\n} else {\nb = append(b, 0)\n}\n", expr)
+	case kindInt:
+		g.use(`"encoding/binary"`)
+		g.printf("b = binary.LittleEndian.AppendUint64(b, uint64(int64(%s)))\n", expr)
+	case kindUint:
+		g.use(`"encoding/binary"`)
+		g.printf("b = binary.LittleEndian.AppendUint64(b, uint64(%s))\n", expr)
+	case kindFloat:
+		g.use(`"encoding/binary"`, `"math"`)
+		g.printf("b = binary.LittleEndian.AppendUint64(b, math.Float64bits(float64(%s)))\n", expr)
+	case kindString:
+		g.use(`"encoding/binary"`)
+		g.printf("b = binary.LittleEndian.AppendUint32(b, uint32(len(%s)))\n", expr)
+		g.printf("b = append(b, %s...)\n", expr)
+	case kindBytes:
+		g.use(`"encoding/binary"`)
+		g.nilSlice(expr)
+		g.printf("b = binary.LittleEndian.AppendUint32(b, uint32(len(%s)))\n", expr)
+		g.printf("b = append(b, %s...)\n}\n", expr)
+	case kindSlice:
+		g.use(`"encoding/binary"`)
+		g.nilSlice(expr)
+		g.printf("b = binary.LittleEndian.AppendUint32(b, uint32(len(%s)))\n", expr)
+		g.printf("for i := range %s {\n", expr)
+		g.marshalField(expr+"[i]", typ.(*ast.ArrayType).Elt)
+		g.printf("}\n}\n")
+	case kindNested:
+		g.printf("if b, err = %s.MarshalStructo(b); err != nil {\nreturn nil, err\n}\n", expr)
+	default:
+		g.use(`structo "github.com/Lucifer07/Structo"`)
+		g.printf("if b, err = structo.AppendField(b, &%s); err != nil {\nreturn nil, err\n}\n", expr)
+	}
+}
+
+// nilSlice writes the -1 length of a nil slice and opens the else branch
+// for the elements.
+func (g *generator) nilSlice(expr string) {
+	g.printf("if %s == nil {\nb = binary.LittleEndian.AppendUint32(b, math.MaxUint32)\n} else {\n", expr)
+	g.use(`"math"`)
+}
+
+func (g *generator) need(size string) {
+	g.use(`"io"`)
+	g.printf("if len(data)-n < %s {\nreturn 0, io.ErrUnexpectedEOF\n}\n", size)
+}
+
+func (g *generator) unmarshalField(expr string, typ ast.Expr) {
+	switch g.kind(typ) {
+	case kindBool:
+		g.need("1")
+		g.printf("%s = data[n] == 1\nn++\n", expr)
+	case kindInt:
+		g.need("8")
+		g.use(`"encoding/binary"`)
+		g.printf("%s = %s(int64(binary.LittleEndian.Uint64(data[n:])))\nn += 8\n", expr, typ.(*ast.Ident).Name)
+	case kindUint:
+		g.need("8")
+		g.use(`"encoding/binary"`)
+		g.printf("%s = %s(binary.LittleEndian.Uint64(data[n:]))\nn += 8\n", expr, typ.(*ast.Ident).Name)
+	case kindFloat:
+		g.need("8")
+		g.use(`"encoding/binary"`, `"math"`)
+		g.printf("%s = %s(math.Float64frombits(binary.LittleEndian.Uint64(data[n:])))\nn += 8\n", expr, typ.(*ast.Ident).Name)
+	case kindString:
+		g.printf("{\n")
+		g.need("4")
+		g.printf("l := int(int32(binary.LittleEndian.Uint32(data[n:])))\nn += 4\n")
+		g.use(`"github.com/Lucifer07/Structo/errdefs"`)
+		g.printf("if l < 0 {\nreturn 0, errdefs.ErrInvalidLength\n}\n")
+		g.need("l")
+		g.printf("%s = string(data[n : n+l])\nn += l\n}\n", expr)
+	case kindBytes:
+		g.printf("{\n")
+		g.need("4")
+		g.printf("l := int(int32(binary.LittleEndian.Uint32(data[n:])))\nn += 4\n")
+		g.printf("switch {\ncase l == -1:\n%s = nil\n", expr)
+		g.use(`"github.com/Lucifer07/Structo/errdefs"`)
+		g.printf("case l < 0:\nreturn 0, errdefs.ErrInvalidLength\n")
+		g.printf("case l > len(data)-n:\nreturn 0, io.ErrUnexpectedEOF\n")
+		g.printf("default:\n%s = make(%s, l)\ncopy(%s, data[n:])\nn += l\n}\n}\n", expr, "[]"+typ.(*ast.ArrayType).Elt.(*ast.Ident).Name, expr)
+	case kindSlice:
+		elem := typ.(*ast.ArrayType).Elt
+		g.printf("{\n")
+		g.need("4")
+		g.printf("l := int(int32(binary.LittleEndian.Uint32(data[n:])))\nn += 4\n")
+		g.printf("switch {\ncase l == -1:\n%s = nil\n", expr)
+		g.use(`"github.com/Lucifer07/Structo/errdefs"`)
+		g.printf("case l < 0:\nreturn 0, errdefs.ErrInvalidLength\n")
+		// every element takes at least one byte, a longer length is corrupt
+		g.printf("case l > len(data)-n:\nreturn 0, io.ErrUnexpectedEOF\n")
+		g.printf("default:\n%s = make([]%s, l)\n", expr, elem.(*ast.Ident).Name)
+		g.printf("for i := range %s {\n", expr)
+		g.unmarshalField(expr+"[i]", elem)
+		g.printf("}\n}\n}\n")
+	case kindNested:
+		g.printf("{\nm, err := %s.UnmarshalStructo(data[n:])\nif err != nil {\nreturn 0, err\n}\nn += m\n}\n", expr)
+	default:
+		g.use(`structo "github.com/Lucifer07/Structo"`)
+		g.printf("{\nm, err := structo.ReadField(data[n:], &%s)\nif err != nil {\nreturn 0, err\n}\nn += m\n}\n", expr)
+	}
+}
+
+// source returns the gofmt-ed file with the imports the methods use.
+func (g *generator) source(pkg string) ([]byte, error) {
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "// Code generated by structogen. DO NOT EDIT.\n\npackage %s\n\nimport (\n", pkg)
+	for _, path := range []string{`"encoding/binary"`, `"io"`, `"math"`, `"reflect"`, "", `structo "github.com/Lucifer07/Structo"`, `"github.com/Lucifer07/Structo/errdefs"`} {
+		if path == "" || g.imports[path] {
+			fmt.Fprintf(&out, "%s\n", path)
+		}
+	}
+	fmt.Fprintf(&out, ")\n\n%s", g.buf.Bytes())
+
+	src, err := format.Source(out.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("formatting generated code: %w", err)
+	}
+	return src, nil
+}
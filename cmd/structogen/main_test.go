@@ -0,0 +1,32 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// The committed generated files must be what the generator produces now,
+// run go generate in their directory after changing either.
+func TestGeneratedFilesUpToDate(t *testing.T) {
+	for _, tc := range []struct {
+		dir, file string
+		types     []string
+	}{
+		{filepath.Join("..", "..", "internal", "gentest"), "fixture_structo.go", []string{"Fixture", "Inner", "Base"}},
+		{filepath.Join("..", "..", "example"), "user_structo.go", []string{"User", "Address", "Metadata"}},
+	} {
+		src, err := generateFile(tc.dir, tc.types)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.dir, err)
+		}
+		stored, err := os.ReadFile(filepath.Join(tc.dir, tc.file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(src, stored) {
+			t.Errorf("%s is out of date, run go generate in %s", tc.file, tc.dir)
+		}
+	}
+}
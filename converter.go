@@ -4,7 +4,7 @@ package structo
 
 import (
 	"bytes"
-	"encoding/binary"
+	"fmt"
 	"reflect"
 	"sync"
 
@@ -15,307 +15,331 @@ import (
 // Converter defines the interface for encoding and decoding structures.
 type Converter interface {
 	StructToBinary(data interface{}) ([]byte, error)
+	AppendBinary(dst []byte, data interface{}) ([]byte, error)
 	BinaryToStruct(data []byte, result interface{}) error
+	BinaryToStructUnsafe(data []byte, result interface{}) error
+	DecodeField(data []byte, sample interface{}, path string) (interface{}, error)
 	EncodeToString(data interface{}) (string, error)
 	DecodeFromString(data string, result interface{}) error
 	EncodeToStringSafe(data interface{}) (string, error)
 	DecodeFromStringSafe(data string, result interface{}) error
+	EncodeToStringSafeWithAD(data interface{}, ad []byte) (string, error)
+	DecodeFromStringSafeWithAD(data string, ad []byte, result interface{}) error
+	Reencrypt(data string) (string, error)
+	ReencryptWithAD(data string, ad []byte) (string, error)
 }
 
 // ConverterImpl is the concrete implementation of the Converter interface.
 type converterImpl struct {
 	bufferPool sync.Pool
-	encryptor  *cha.EncryptData
+	encryptor  Encryptor
+	flags      uint8
+	decodeOpts DecodeOptions
+	// codec replaces the native binary format, see WithCodec.
+	codec Codec
+	// text encodes the string methods, nil selects their defaults.
+	text TextEncoding
+	// compression of StructToBinary, see WithCompression.
+	compression       Compression
+	compressThreshold int
+	// encryptorErr is returned by the Safe methods when the configured
+	// encryptor could not be built, see WithKey.
+	encryptorErr error
 }
 
 // NewConverter creates and returns a new instance of ConverterImpl.
-func NewConverter() Converter {
-	return &converterImpl{
+func NewConverter(opts ...ConverterOption) Converter {
+	c := &converterImpl{
 		bufferPool: sync.Pool{
 			New: func() interface{} {
 				return new(bytes.Buffer)
 			},
 		},
-		encryptor: cha.NewEncryptor(),
+		encryptor: encryptData{cha.NewEncryptor()},
 	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
 }
 
 // StructToBinary converts a struct to its binary representation.
+// The output starts with a header carrying the format version and a
+// fingerprint of the encoded type, see converter_header.go. A Converter
+// configured WithCodec returns the codec's encoding instead, and one
+// configured WithCompression wraps the output in a compressed frame.
+// The returned slice is owned by the caller.
 func (c *converterImpl) StructToBinary(data interface{}) ([]byte, error) {
+	return c.AppendBinary(nil, data)
+}
+
+// AppendBinary appends the output of StructToBinary to dst and returns the
+// extended slice. Reusing dst across calls saves allocating the returned
+// slice; the encoding itself still allocates, as StructToBinary does.
+func (c *converterImpl) AppendBinary(dst []byte, data interface{}) ([]byte, error) {
+	if c.codec != nil {
+		bin, err := c.codec.Marshal(data)
+		if err != nil {
+			return dst, err
+		}
+		if bin, err = c.compress(bin); err != nil {
+			return dst, err
+		}
+		return append(dst, bin...), nil
+	}
+
+	// the pooled buffer is reused by the next call, copy the result out
 	buf := c.getBuffer()
 	defer c.putBuffer(buf)
 
-	if err := c.encodeValue(buf, reflect.ValueOf(data)); err != nil {
-		return nil, err
+	if err := c.writeBinary(buf, data); err != nil {
+		return dst, err
+	}
+	bin, err := c.compress(buf.Bytes())
+	if err != nil {
+		return dst, err
+	}
+	return append(dst, bin...), nil
+}
+
+// writeBinary writes the header and the encoding of data to buf.
+func (c *converterImpl) writeBinary(buf *bytes.Buffer, data interface{}) error {
+	dataType := reflect.TypeOf(data)
+	if dataType == nil {
+		return errdefs.ErrUnsupportedKind
+	}
+
+	writeHeader(buf, header{
+		version: formatVersion,
+		flags:   c.flags,
+		schema:  schemaHash(dataType, c.flags),
+	})
+	// encode from an addressable copy, so unexported fields can be read.
+	// Pointers to the top level value are transparent.
+	v := reflect.ValueOf(data)
+	for v.Kind() == reflect.Ptr && !v.IsNil() && v.Elem().Kind() == reflect.Ptr {
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Ptr {
+		ptr := reflect.New(v.Type())
+		ptr.Elem().Set(v)
+		v = ptr
+	}
+	if v.IsNil() {
+		return errdefs.ErrUnsupportedKind
 	}
-	return buf.Bytes(), nil
+	e := &encodeState{Buffer: buf, flags: c.flags}
+	if c.flags&flagReferences != 0 {
+		e.refs = map[refKey]int{{ptr: v.Pointer(), typ: v.Type()}: 0}
+	}
+	return e.encodeValue(v.Elem())
 }
 
 // BinaryToStruct converts binary data back into the provided struct pointer.
+// Data that lacks a valid header, was written by an unsupported format
+// version or was encoded from a different type is rejected. Errors in the
+// body are reported as *errdefs.DecodeError, including bytes left over
+// after the value unless DecodeOptions.Lenient is set. Compressed data is
+// inflated first.
 func (c *converterImpl) BinaryToStruct(data []byte, result interface{}) error {
-	if reflect.TypeOf(result).Kind() != reflect.Ptr {
+	return c.binaryToStruct(data, result, false)
+}
+
+// BinaryToStructUnsafe decodes like BinaryToStruct without copying: decoded
+// strings and byte slices, also inside collections, alias data. data must
+// not be modified while result is in use, or its strings change as well.
+// Values read by codecs, marshalers, generated code and non-binary Codecs
+// are still copied, as is compressed data, which is inflated into a new
+// buffer first.
+func (c *converterImpl) BinaryToStructUnsafe(data []byte, result interface{}) error {
+	return c.binaryToStruct(data, result, true)
+}
+
+func (c *converterImpl) binaryToStruct(data []byte, result interface{}, alias bool) error {
+	resultType := reflect.TypeOf(result)
+	if resultType == nil || resultType.Kind() != reflect.Ptr || reflect.ValueOf(result).IsNil() {
 		return errdefs.ErrNotPointerToStruct
 	}
 
-	reader := bytes.NewReader(data)
-	return c.decodeValue(reader, reflect.ValueOf(result).Elem())
+	if err := c.decodeOpts.checkSize(uint64(len(data))); err != nil {
+		return err
+	}
+	data, err := c.decompress(data)
+	if err != nil {
+		return err
+	}
+	if c.codec != nil {
+		return c.codec.Unmarshal(data, result)
+	}
+	h, err := readHeader(data)
+	if err != nil {
+		return err
+	}
+	if h.schema != schemaHash(resultType, h.flags) {
+		return errdefs.ErrSchemaMismatch
+	}
+	v := reflect.ValueOf(result)
+	for v.Elem().Kind() == reflect.Ptr {
+		if v.Elem().IsNil() {
+			v.Elem().Set(reflect.New(v.Type().Elem().Elem()))
+		}
+		v = v.Elem()
+	}
+	d := newDecodeState(data[headerSize:], h.flags)
+	d.opts = c.decodeOpts
+	d.base = headerSize
+	d.alias = alias
+	if h.flags&flagReferences != 0 {
+		d.refs = []reflect.Value{v}
+	}
+	if err := d.decodeValue(v.Elem()); err != nil {
+		return err
+	}
+	if d.Len() > 0 && !c.decodeOpts.Lenient {
+		return &errdefs.DecodeError{Offset: d.offset(), Err: fmt.Errorf("%w: %d bytes", errdefs.ErrTrailingData, d.Len())}
+	}
+	return nil
 }
 
-// EncodeToString converts a struct to a raw string representation.
+// EncodeToString converts a struct to a string representation, the raw
+// binary unless a TextEncoding is configured.
 func (c *converterImpl) EncodeToString(data interface{}) (string, error) {
 	bin, err := c.StructToBinary(data)
 	if err != nil {
 		return "", err
 	}
-	return string(bin), nil
+	return c.textEncoding(RawText).EncodeToString(bin), nil
 }
 
-// DecodeFromString decodes a raw string back into a struct.
+// DecodeFromString decodes a string produced by EncodeToString back into a struct.
 func (c *converterImpl) DecodeFromString(data string, result interface{}) error {
-	return c.BinaryToStruct([]byte(data), result)
+	bin, err := c.decodeText(data, RawText)
+	if err != nil {
+		return err
+	}
+	return c.BinaryToStruct(bin, result)
 }
 
-// EncodeToStringSafe converts a struct to an encrypted string representation.
+// EncodeToStringSafe converts a struct to an encrypted string representation,
+// standard base64 unless a TextEncoding is configured.
 func (c *converterImpl) EncodeToStringSafe(data interface{}) (string, error) {
+	return c.EncodeToStringSafeWithAD(data, nil)
+}
+
+// DecodeToStringSafe decrypts and decodes an encrypted string into a struct.
+// Data that was tampered with or encrypted with another key fails with
+// errdefs.ErrDecryptionFailed.
+func (c *converterImpl) DecodeFromStringSafe(data string, result interface{}) error {
+	return c.DecodeFromStringSafeWithAD(data, nil, result)
+}
+
+// EncodeToStringSafeWithAD is EncodeToStringSafe binding the ciphertext to
+// ad, the context the value belongs to such as a tenant or record id. ad is
+// authenticated but not stored: DecodeFromStringSafeWithAD must be given
+// the same ad, so a string copied to another record fails to decrypt.
+func (c *converterImpl) EncodeToStringSafeWithAD(data interface{}, ad []byte) (string, error) {
+	if c.encryptorErr != nil {
+		return "", c.encryptorErr
+	}
 	bin, err := c.StructToBinary(data)
 	if err != nil {
 		return "", err
 	}
-	return c.encryptor.Encrypt(bin)
+	return c.encrypt(bin, ad)
 }
 
-// DecodeToStringSafe decrypts and decodes an encrypted string into a struct.
-func (c *converterImpl) DecodeFromStringSafe(data string, result interface{}) error {
-	plain, err := c.encryptor.Decrypt(data)
+// DecodeFromStringSafeWithAD decrypts a string produced by
+// EncodeToStringSafeWithAD with the same ad and decodes it into result. A
+// different ad fails with errdefs.ErrDecryptionFailed.
+func (c *converterImpl) DecodeFromStringSafeWithAD(data string, ad []byte, result interface{}) error {
+	plain, err := c.decrypt(data, ad)
 	if err != nil {
 		return err
 	}
-	return c.BinaryToStruct([]byte(plain), result)
-}
-
-// Internal helpers
-
-func (c *converterImpl) getBuffer() *bytes.Buffer {
-	buf := c.bufferPool.Get().(*bytes.Buffer)
-	buf.Reset()
-	return buf
+	return c.BinaryToStruct(plain, result)
 }
 
-func (c *converterImpl) putBuffer(buf *bytes.Buffer) {
-	c.bufferPool.Put(buf)
+// Reencrypt decrypts a string produced by EncodeToStringSafe and encrypts
+// it again, with the active key when the Converter uses a keyring. It is
+// meant for migrating stored data after a key rotation. The payload is not
+// decoded.
+func (c *converterImpl) Reencrypt(data string) (string, error) {
+	return c.ReencryptWithAD(data, nil)
 }
 
-func (c *converterImpl) encodeValue(buf *bytes.Buffer, v reflect.Value) error {
-	if v.Kind() == reflect.Ptr {
-		v = v.Elem()
+// ReencryptWithAD is Reencrypt for strings bound to ad, which stays bound
+// to the same ad.
+func (c *converterImpl) ReencryptWithAD(data string, ad []byte) (string, error) {
+	plain, err := c.decrypt(data, ad)
+	if err != nil {
+		return "", err
 	}
+	return c.encrypt(plain, ad)
+}
 
-	switch v.Kind() {
-	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-		return binary.Write(buf, binary.LittleEndian, v.Int())
-	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-		return binary.Write(buf, binary.LittleEndian, v.Uint())
-	case reflect.Float32, reflect.Float64:
-		return binary.Write(buf, binary.LittleEndian, v.Float())
-	case reflect.Bool:
-		var b byte
-		if v.Bool() {
-			b = 1
-		}
-		return buf.WriteByte(b)
-	case reflect.String:
-		str := v.String()
-		if err := binary.Write(buf, binary.LittleEndian, int32(len(str))); err != nil {
-			return err
-		}
-		_, err := buf.WriteString(str)
-		return err
-	case reflect.Struct:
-		for i := 0; i < v.NumField(); i++ {
-			if err := c.encodeValue(buf, v.Field(i)); err != nil {
-				return err
-			}
-		}
-		return nil
-	case reflect.Slice, reflect.Array:
-		length := v.Len()
-		if err := binary.Write(buf, binary.LittleEndian, int32(length)); err != nil {
-			return err
-		}
-		for i := 0; i < length; i++ {
-			if err := c.encodeValue(buf, v.Index(i)); err != nil {
-				return err
-			}
-		}
-		return nil
-
-	case reflect.Map:
-		if v.IsNil() {
-			if err := binary.Write(buf, binary.LittleEndian, int32(-1)); err != nil {
-				return err
-			}
-			return nil
-		}
-		keys := v.MapKeys()
-		if err := binary.Write(buf, binary.LittleEndian, int32(len(keys))); err != nil {
-			return err
-		}
-		for _, key := range keys {
-			if err := c.encodeValue(buf, key); err != nil {
-				return err
-			}
-			if err := c.encodeValue(buf, v.MapIndex(key)); err != nil {
-				return err
-			}
-		}
-		return nil
-
-	case reflect.Interface:
-		if v.IsNil() {
-			if err := binary.Write(buf, binary.LittleEndian, int32(-1)); err != nil {
-				return err
-			}
-			return nil
-		}
-		return c.encodeValue(buf, v.Elem())
+// Internal helpers
 
-	default:
-		return errdefs.ErrUnsupportedKind
+func (c *converterImpl) textEncoding(def TextEncoding) TextEncoding {
+	if c.text == nil {
+		return def
 	}
+	return c.text
 }
 
-func (c *converterImpl) decodeValue(buf *bytes.Reader, v reflect.Value) error {
-	switch v.Kind() {
-	case reflect.Ptr:
-		if v.IsNil() {
-			v.Set(reflect.New(v.Type().Elem()))
-		}
-		return c.decodeValue(buf, v.Elem())
-	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-		return readAndSetInt(buf, v)
-	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-		return readAndSetUint(buf, v)
-	case reflect.Float32, reflect.Float64:
-		return readAndSetFloat(buf, v)
-	case reflect.Bool:
-		var b byte
-		if err := binary.Read(buf, binary.LittleEndian, &b); err != nil {
-			return err
-		}
-		v.SetBool(b == 1)
-		return nil
-	case reflect.String:
-		var length int32
-		if err := binary.Read(buf, binary.LittleEndian, &length); err != nil {
-			return err
-		}
-		strBuf := make([]byte, length)
-		if _, err := buf.Read(strBuf); err != nil {
-			return err
-		}
-		v.SetString(string(strBuf))
-		return nil
-	case reflect.Struct:
-		for i := 0; i < v.NumField(); i++ {
-			if err := c.decodeValue(buf, v.Field(i)); err != nil {
-				return err
-			}
-		}
-		return nil
-	case reflect.Slice:
-		var length int32
-		if err := binary.Read(buf, binary.LittleEndian, &length); err != nil {
-			return err
-		}
-		if length == -1 {
-			v.Set(reflect.Zero(v.Type()))
-			return nil
-		}
-		slice := reflect.MakeSlice(v.Type(), int(length), int(length))
-		for i := 0; i < int(length); i++ {
-			if err := c.decodeValue(buf, slice.Index(i)); err != nil {
-				return err
-			}
-		}
-		v.Set(slice)
-		return nil
-
-	case reflect.Array:
-		for i := 0; i < v.Len(); i++ {
-			if err := c.decodeValue(buf, v.Index(i)); err != nil {
-				return err
-			}
-		}
-		return nil
-
-	case reflect.Map:
-		var length int32
-		if err := binary.Read(buf, binary.LittleEndian, &length); err != nil {
-			return err
-		}
-		if length == -1 {
-			v.Set(reflect.Zero(v.Type()))
-			return nil
-		}
-		mapType := v.Type()
-		newMap := reflect.MakeMap(mapType)
-		for i := 0; i < int(length); i++ {
-			key := reflect.New(mapType.Key()).Elem()
-			val := reflect.New(mapType.Elem()).Elem()
-			if err := c.decodeValue(buf, key); err != nil {
-				return err
-			}
-			if err := c.decodeValue(buf, val); err != nil {
-				return err
-			}
-			newMap.SetMapIndex(key, val)
-		}
-		v.Set(newMap)
-		return nil
-
-	case reflect.Interface:
-		var length int32
-		if err := binary.Read(buf, binary.LittleEndian, &length); err != nil {
-			return err
-		}
-		if length == -1 {
-			v.Set(reflect.Zero(v.Type()))
-			return nil
-		}
-		elem := reflect.New(v.Type()).Elem()
-		if err := c.decodeValue(buf, elem); err != nil {
-			return err
-		}
-		v.Set(elem)
-		return nil
-	default:
-		return errdefs.ErrUnsupportedKind
+func (c *converterImpl) decodeText(data string, def TextEncoding) ([]byte, error) {
+	bin, err := c.textEncoding(def).DecodeString(data)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errdefs.ErrInvalidTextEncoding, err)
 	}
+	return bin, nil
 }
 
-func readAndSetInt(buf *bytes.Reader, v reflect.Value) error {
-	var val int64
-	if err := binary.Read(buf, binary.LittleEndian, &val); err != nil {
-		return err
+func (c *converterImpl) encrypt(plain, ad []byte) (string, error) {
+	var ciphertext []byte
+	var err error
+	if len(ad) == 0 {
+		ciphertext, err = c.encryptor.Encrypt(plain)
+	} else if enc, ok := c.encryptor.(EncryptorWithAD); ok {
+		ciphertext, err = enc.EncryptWithAD(plain, ad)
+	} else {
+		err = fmt.Errorf("%w: %T does not take associated data", errdefs.ErrNotSupported, c.encryptor)
 	}
-	v.SetInt(val)
-	return nil
+	if err != nil {
+		return "", err
+	}
+	return c.textEncoding(Base64Std).EncodeToString(ciphertext), nil
 }
 
-func readAndSetUint(buf *bytes.Reader, v reflect.Value) error {
-	var val uint64
-	if err := binary.Read(buf, binary.LittleEndian, &val); err != nil {
-		return err
+func (c *converterImpl) decrypt(data string, ad []byte) ([]byte, error) {
+	if c.encryptorErr != nil {
+		return nil, c.encryptorErr
 	}
-	v.SetUint(val)
-	return nil
+	ciphertext, err := c.decodeText(data, Base64Std)
+	if err != nil {
+		return nil, err
+	}
+	if len(ad) == 0 {
+		return c.encryptor.Decrypt(ciphertext)
+	}
+	if enc, ok := c.encryptor.(EncryptorWithAD); ok {
+		return enc.DecryptWithAD(ciphertext, ad)
+	}
+	return nil, fmt.Errorf("%w: %T does not take associated data", errdefs.ErrNotSupported, c.encryptor)
 }
 
-func readAndSetFloat(buf *bytes.Reader, v reflect.Value) error {
-	var val float64
-	if err := binary.Read(buf, binary.LittleEndian, &val); err != nil {
-		return err
+func (c *converterImpl) getBuffer() *bytes.Buffer {
+	buf := c.bufferPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	return buf
+}
+
+// maxPooledBuffer is the capacity above which buffers are left to the
+// garbage collector, so a single huge value does not stay pinned in the pool.
+const maxPooledBuffer = 1 << 16
+
+func (c *converterImpl) putBuffer(buf *bytes.Buffer) {
+	if buf.Cap() > maxPooledBuffer {
+		return
 	}
-	v.SetFloat(val)
-	return nil
+	c.bufferPool.Put(buf)
 }
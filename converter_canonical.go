@@ -0,0 +1,63 @@
+package structo
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"reflect"
+	"sort"
+)
+
+// canonicalConverter backs Hash.
+var canonicalConverter = NewConverter(WithCanonical()).(*converterImpl)
+
+// Hash returns the SHA-256 of the canonical encoding of data, header
+// included. Equal values of the same type hash equal, which makes the
+// result usable as a cache or deduplication key. The hash changes with the
+// format version.
+func Hash(data interface{}) ([32]byte, error) {
+	var buf bytes.Buffer
+	if err := canonicalConverter.writeBinary(&buf, data); err != nil {
+		return [32]byte{}, err
+	}
+	return sha256.Sum256(buf.Bytes()), nil
+}
+
+type mapEntry struct {
+	key     reflect.Value
+	encoded []byte
+}
+
+// encodeCanonicalMap writes the entries of map v ordered by the encoding of
+// their keys, so equal maps always produce the same bytes.
+func (e *encodeState) encodeCanonicalMap(v reflect.Value) error {
+	entries := make([]mapEntry, 0, v.Len())
+	iter := v.MapRange()
+	for iter.Next() {
+		// back references depend on the write order, sort without them
+		sub := &encodeState{Buffer: new(bytes.Buffer), flags: e.flags &^ flagReferences}
+		if err := sub.encodeValue(iter.Key()); err != nil {
+			return err
+		}
+		entries = append(entries, mapEntry{key: iter.Key(), encoded: sub.Bytes()})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return bytes.Compare(entries[i].encoded, entries[j].encoded) < 0
+	})
+
+	if err := e.writeLen(len(entries)); err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if e.flags&flagReferences != 0 {
+			if err := e.encodeValue(entry.key); err != nil {
+				return err
+			}
+		} else if _, err := e.Write(entry.encoded); err != nil {
+			return err
+		}
+		if err := e.encodeValue(v.MapIndex(entry.key)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+package structo
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/vmihailenco/msgpack/v5"
+
+	"github.com/Lucifer07/Structo/errdefs"
+)
+
+// Codec is a serialization format a Converter can be switched to with
+// WithCodec. Marshal returns a buffer owned by the caller, Unmarshal decodes
+// into the pointer v and rejects data left over after the value.
+type Codec interface {
+	// Name identifies the format, e.g. "msgpack".
+	Name() string
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+var (
+	// Binary is the native structo format, the default. Used through a
+	// Converter it follows the Converter's options.
+	Binary Codec = binaryCodec{}
+	// JSON writes canonical JSON: struct fields in declaration order, map
+	// keys sorted, no HTML escaping and no trailing newline.
+	JSON Codec = jsonCodec{}
+	// MsgPack writes MessagePack with sorted map keys and integers in their
+	// shortest form. Struct fields follow `msgpack` tags. time.Time uses the
+	// timestamp extension, which keeps the instant but not the zone.
+	MsgPack Codec = msgpackCodec{}
+	// CBOR writes deterministic CBOR (RFC 8949 core deterministic encoding)
+	// with time.Time as RFC 3339 text. Struct fields follow `cbor` or `json`
+	// tags.
+	CBOR Codec = cborCodec{}
+)
+
+type binaryCodec struct{}
+
+// binaryConverter backs the Binary codec, with default options.
+var binaryConverter = NewConverter().(*converterImpl)
+
+func (binaryCodec) Name() string { return "binary" }
+
+func (binaryCodec) Marshal(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := binaryConverter.writeBinary(&buf, v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (binaryCodec) Unmarshal(data []byte, v interface{}) error {
+	return binaryConverter.BinaryToStruct(data, v)
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) Name() string { return "json" }
+
+func (jsonCodec) Marshal(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+func (jsonCodec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+type msgpackCodec struct{}
+
+func (msgpackCodec) Name() string { return "msgpack" }
+
+func (msgpackCodec) Marshal(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := msgpack.NewEncoder(&buf)
+	enc.SetSortMapKeys(true)
+	enc.UseCompactInts(true)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (msgpackCodec) Unmarshal(data []byte, v interface{}) error {
+	r := bytes.NewReader(data)
+	if err := msgpack.NewDecoder(r).Decode(v); err != nil {
+		return err
+	}
+	if r.Len() > 0 {
+		return fmt.Errorf("%w: %d bytes", errdefs.ErrTrailingData, r.Len())
+	}
+	return nil
+}
+
+type cborCodec struct{}
+
+var cborEncMode, cborDecMode = func() (cbor.EncMode, cbor.DecMode) {
+	opts := cbor.CoreDetEncOptions()
+	opts.Time = cbor.TimeRFC3339Nano
+	enc, err := opts.EncMode()
+	if err != nil {
+		panic(err)
+	}
+	dec, err := cbor.DecOptions{}.DecMode()
+	if err != nil {
+		panic(err)
+	}
+	return enc, dec
+}()
+
+func (cborCodec) Name() string { return "cbor" }
+
+func (cborCodec) Marshal(v interface{}) ([]byte, error) {
+	return cborEncMode.Marshal(v)
+}
+
+func (cborCodec) Unmarshal(data []byte, v interface{}) error {
+	return cborDecMode.Unmarshal(data, v)
+}
@@ -0,0 +1,50 @@
+package structo
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+type codecSample struct {
+	ID    int64
+	Name  string
+	Tags  []string
+	Attrs map[string]int
+}
+
+func TestCodecsRoundTrip(t *testing.T) {
+	in := codecSample{ID: 42, Name: "structo", Tags: []string{"a", "b"}, Attrs: map[string]int{"x": 1}}
+
+	for _, codec := range []Codec{Binary, JSON, MsgPack, CBOR} {
+		t.Run(codec.Name(), func(t *testing.T) {
+			blob, err := codec.Marshal(in)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var out codecSample
+			if err := codec.Unmarshal(blob, &out); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(out, in) {
+				t.Errorf("got %+v, want %+v", out, in)
+			}
+		})
+	}
+}
+
+// The Binary codec writes what a default Converter writes.
+func TestBinaryCodecMatchesConverter(t *testing.T) {
+	in := codecSample{ID: 1, Name: "x"}
+	want, err := NewConverter().StructToBinary(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := Binary.Marshal(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("Binary.Marshal = %x, want %x", got, want)
+	}
+}
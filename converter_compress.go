@@ -0,0 +1,146 @@
+package structo
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/Lucifer07/Structo/errdefs"
+)
+
+// Compression selects the algorithm of WithCompression.
+type Compression uint8
+
+const (
+	NoCompression Compression = iota
+	// Gzip compresses with compress/gzip (RFC 1952).
+	Gzip
+	// Flate compresses with compress/flate (raw DEFLATE, RFC 1951), the
+	// smallest framing of the three.
+	Flate
+	// Zlib compresses with compress/zlib (RFC 1950).
+	Zlib
+)
+
+// A compressed blob wraps the output of the codec in a frame of its own:
+//
+//	magic     [4]byte "STRZ"
+//	algorithm uint8   Compression
+//	size      uvarint length of the uncompressed blob
+//	data      compressed blob
+//
+// BinaryToStruct recognizes the frame whatever the Converter's options are.
+var compressMagic = [4]byte{'S', 'T', 'R', 'Z'}
+
+func (alg Compression) String() string {
+	switch alg {
+	case NoCompression:
+		return "none"
+	case Gzip:
+		return "gzip"
+	case Flate:
+		return "flate"
+	case Zlib:
+		return "zlib"
+	}
+	return fmt.Sprintf("Compression(%d)", uint8(alg))
+}
+
+func (alg Compression) newWriter(w io.Writer) io.WriteCloser {
+	switch alg {
+	case Gzip:
+		return gzip.NewWriter(w)
+	case Flate:
+		fw, _ := flate.NewWriter(w, flate.DefaultCompression)
+		return fw
+	case Zlib:
+		return zlib.NewWriter(w)
+	}
+	return nil
+}
+
+func (alg Compression) newReader(r io.Reader) (io.ReadCloser, error) {
+	switch alg {
+	case Gzip:
+		return gzip.NewReader(r)
+	case Flate:
+		return flate.NewReader(r), nil
+	case Zlib:
+		return zlib.NewReader(r)
+	}
+	return nil, fmt.Errorf("unknown algorithm %d", uint8(alg))
+}
+
+// compress wraps blob in a compressed frame. Blobs below the threshold, and
+// those compression does not make smaller, are returned unchanged.
+func (c *converterImpl) compress(blob []byte) ([]byte, error) {
+	if c.compression == NoCompression || len(blob) < c.compressThreshold {
+		return blob, nil
+	}
+
+	var buf bytes.Buffer
+	buf.Write(compressMagic[:])
+	buf.WriteByte(byte(c.compression))
+	buf.Write(binary.AppendUvarint(nil, uint64(len(blob))))
+	w := c.compression.newWriter(&buf)
+	if w == nil {
+		return nil, fmt.Errorf("%w: unknown algorithm %d", errdefs.ErrInvalidCompression, uint8(c.compression))
+	}
+	if _, err := w.Write(blob); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	if buf.Len() >= len(blob) {
+		return blob, nil
+	}
+	return buf.Bytes(), nil
+}
+
+// decompress unwraps a compressed frame, other data is returned unchanged.
+// The uncompressed size is checked against MaxBytes, or
+// DefaultMaxInflatedBytes, before inflating, and inflating stops at the
+// recorded size.
+func (c *converterImpl) decompress(data []byte) ([]byte, error) {
+	if len(data) < len(compressMagic) || !bytes.Equal(data[:len(compressMagic)], compressMagic[:]) {
+		return data, nil
+	}
+	if len(data) < len(compressMagic)+1 {
+		return nil, fmt.Errorf("%w: truncated frame", errdefs.ErrInvalidCompression)
+	}
+
+	alg := Compression(data[len(compressMagic)])
+	size, n := binary.Uvarint(data[len(compressMagic)+1:])
+	if n <= 0 || size > math.MaxInt32 {
+		return nil, fmt.Errorf("%w: invalid size", errdefs.ErrInvalidCompression)
+	}
+	if err := c.decodeOpts.checkInflatedSize(size); err != nil {
+		return nil, err
+	}
+
+	r, err := alg.newReader(bytes.NewReader(data[len(compressMagic)+1+n:]))
+	if err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, fmt.Errorf("%w: %v", errdefs.ErrInvalidCompression, err)
+	}
+	defer r.Close()
+
+	// the buffer grows with the data actually inflated, so a forged size
+	// does not allocate up front
+	var buf bytes.Buffer
+	if _, err := io.Copy(&buf, io.LimitReader(r, int64(size)+1)); err != nil {
+		return nil, fmt.Errorf("%w: %v", errdefs.ErrInvalidCompression, err)
+	}
+	if uint64(buf.Len()) != size {
+		return nil, fmt.Errorf("%w: inflated to %d bytes, want %d", errdefs.ErrInvalidCompression, buf.Len(), size)
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,47 @@
+package structo
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"testing"
+
+	"github.com/Lucifer07/Structo/errdefs"
+)
+
+// forgedFrame returns a zlib frame declaring size bytes around n zero bytes.
+func forgedFrame(t *testing.T, size uint64, n int) []byte {
+	t.Helper()
+	frame := append([]byte(nil), compressMagic[:]...)
+	frame = append(frame, byte(Zlib))
+	frame = binary.AppendUvarint(frame, size)
+	var buf bytes.Buffer
+	w := zlib.NewWriter(&buf)
+	if _, err := w.Write(make([]byte, n)); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return append(frame, buf.Bytes()...)
+}
+
+// Without MaxBytes compressed blobs are capped at DefaultMaxInflatedBytes
+// before anything is inflated.
+func TestDecompressDefaultLimit(t *testing.T) {
+	var out struct{ A int }
+
+	err := NewConverter().BinaryToStruct(forgedFrame(t, 1<<31-1, 1024), &out)
+	if !errors.Is(err, errdefs.ErrDataTooLarge) {
+		t.Fatalf("forged size: got %v, want ErrDataTooLarge", err)
+	}
+
+	// a larger MaxBytes lifts the default, the frame then fails as it
+	// inflates to less than it declares
+	conv := NewConverter(WithDecodeOptions(DecodeOptions{MaxBytes: 1 << 31}))
+	err = conv.BinaryToStruct(forgedFrame(t, DefaultMaxInflatedBytes+1, 1024), &out)
+	if !errors.Is(err, errdefs.ErrInvalidCompression) {
+		t.Fatalf("with MaxBytes: got %v, want ErrInvalidCompression", err)
+	}
+}
@@ -0,0 +1,341 @@
+package structo
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"strconv"
+	"unsafe"
+
+	"github.com/Lucifer07/Structo/errdefs"
+)
+
+// decodeState carries the reader and the layout read from the header.
+type decodeState struct {
+	*bytes.Reader
+	data  []byte
+	flags uint8
+	// pointers read so far, with flagReferences
+	refs  []reflect.Value
+	opts  DecodeOptions
+	depth int
+	// offset of data in the input, for error reports
+	base int
+	err  *errdefs.DecodeError
+	// strings and byte slices alias data, see BinaryToStructUnsafe
+	alias bool
+}
+
+func newDecodeState(data []byte, flags uint8) *decodeState {
+	return &decodeState{
+		Reader: bytes.NewReader(data),
+		data:   data,
+		flags:  flags,
+	}
+}
+
+// rest returns the unread part of the input.
+func (d *decodeState) rest() []byte {
+	return d.data[len(d.data)-d.Len():]
+}
+
+// offset returns the position of the reader in the input.
+func (d *decodeState) offset() int {
+	return d.base + len(d.data) - d.Len()
+}
+
+func (d *decodeState) decodeValue(v reflect.Value) error {
+	err := d.enter()
+	if err == nil {
+		err = d.decodeKind(v)
+	}
+	d.leave()
+	if err != nil && err != error(d.err) {
+		return d.fail(err)
+	}
+	return err
+}
+
+// fail records the offset the first error occurred at. Running out of
+// input is reported as ErrTruncated.
+func (d *decodeState) fail(err error) error {
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		err = errdefs.ErrTruncated
+	}
+	d.err = &errdefs.DecodeError{Offset: d.offset(), Err: err}
+	return d.err
+}
+
+// at prefixes the path of the recorded error with elem, the field name or
+// index of the value that failed to decode.
+func (d *decodeState) at(err error, elem string) error {
+	if err != error(d.err) {
+		return err
+	}
+	if d.err.Path == "" {
+		d.err.Path = elem
+	} else {
+		d.err.Path = joinKey(elem, d.err.Path)
+	}
+	return err
+}
+
+// atKey annotates err from decoding a map key. A key has no path of its
+// own, so the error is reported at the map: the path of a value inside the
+// key is dropped.
+func (d *decodeState) atKey(err error) error {
+	if err == error(d.err) {
+		d.err.Path = ""
+	}
+	return err
+}
+
+func (d *decodeState) decodeKind(v reflect.Value) error {
+	if codec, ok := lookupTypeCodec(v.Type()); ok {
+		return d.decodeTypeCodec(codec, v)
+	}
+	if kind := marshalKindOf(v.Type()); kind != marshalNone {
+		return d.decodeMarshaler(kind, v)
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr:
+		return d.decodePointer(v)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		val, err := d.readInt()
+		if err != nil {
+			return err
+		}
+		v.SetInt(val)
+		return nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		val, err := d.readUint()
+		if err != nil {
+			return err
+		}
+		v.SetUint(val)
+		return nil
+	case reflect.Float32, reflect.Float64:
+		val, err := d.readFloat(v.Kind())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(val)
+		return nil
+	case reflect.Bool:
+		b, err := d.ReadByte()
+		if err != nil {
+			return err
+		}
+		v.SetBool(b == 1)
+		return nil
+	case reflect.String:
+		length, err := d.readLen()
+		if err != nil {
+			return err
+		}
+		strBuf, err := d.readBytesAlias(length)
+		if err != nil {
+			return err
+		}
+		if d.alias && length > 0 {
+			v.SetString(unsafe.String(&strBuf[0], length))
+			return nil
+		}
+		v.SetString(string(strBuf))
+		return nil
+	case reflect.Struct:
+		if u, ok := d.generatedUnmarshaler(v); ok {
+			n, err := u.UnmarshalStructo(d.rest())
+			if err != nil {
+				return err
+			}
+			_, err = d.Seek(int64(n), io.SeekCurrent)
+			return err
+		}
+		if d.flags&flagTagged != 0 {
+			return d.decodeTaggedStruct(v)
+		}
+		return d.decodeStruct(v)
+	case reflect.Slice:
+		length, err := d.readLen()
+		if err != nil {
+			return err
+		}
+		if length == -1 {
+			v.Set(reflect.Zero(v.Type()))
+			return nil
+		}
+		if err := d.checkLen(length, v.Type().Elem()); err != nil {
+			return err
+		}
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			blob, err := d.readBytesAlias(length)
+			if err != nil {
+				return err
+			}
+			v.SetBytes(blob)
+			return nil
+		}
+		slice := reflect.MakeSlice(v.Type(), length, length)
+		for i := 0; i < length; i++ {
+			if err := d.decodeValue(slice.Index(i)); err != nil {
+				return d.at(err, strconv.Itoa(i))
+			}
+		}
+		v.Set(slice)
+		return nil
+
+	case reflect.Array:
+		length, err := d.readLen()
+		if err != nil {
+			return err
+		}
+		if err := d.checkLen(length, v.Type().Elem()); err != nil {
+			return err
+		}
+		if length != v.Len() {
+			return fmt.Errorf("%w: array of %d elements, got %d", errdefs.ErrSchemaMismatch, v.Len(), length)
+		}
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			for i := 0; i < length; i++ {
+				b, err := d.ReadByte()
+				if err != nil {
+					return err
+				}
+				v.Index(i).SetUint(uint64(b))
+			}
+			return nil
+		}
+		for i := 0; i < v.Len(); i++ {
+			if err := d.decodeValue(v.Index(i)); err != nil {
+				return d.at(err, strconv.Itoa(i))
+			}
+		}
+		return nil
+
+	case reflect.Map:
+		length, err := d.readLen()
+		if err != nil {
+			return err
+		}
+		if length == -1 {
+			v.Set(reflect.Zero(v.Type()))
+			return nil
+		}
+		mapType := v.Type()
+		if err := d.checkLen(length, mapType.Key(), mapType.Elem()); err != nil {
+			return err
+		}
+		newMap := reflect.MakeMap(mapType)
+		for i := 0; i < length; i++ {
+			key := reflect.New(mapType.Key()).Elem()
+			val := reflect.New(mapType.Elem()).Elem()
+			if err := d.decodeValue(key); err != nil {
+				return d.atKey(err)
+			}
+			if err := d.decodeValue(val); err != nil {
+				return d.at(err, fmt.Sprint(key))
+			}
+			newMap.SetMapIndex(key, val)
+		}
+		v.Set(newMap)
+		return nil
+
+	case reflect.Interface:
+		return d.decodeInterface(v)
+	default:
+		return errdefs.ErrUnsupportedKind
+	}
+}
+
+// Primitive readers, the counterparts of the encodeState writers.
+
+func (d *decodeState) readInt() (int64, error) {
+	if d.flags&flagCompact != 0 {
+		return binary.ReadVarint(d)
+	}
+	var val int64
+	err := binary.Read(d, binary.LittleEndian, &val)
+	return val, err
+}
+
+func (d *decodeState) readUint() (uint64, error) {
+	if d.flags&flagCompact != 0 {
+		return binary.ReadUvarint(d)
+	}
+	var val uint64
+	err := binary.Read(d, binary.LittleEndian, &val)
+	return val, err
+}
+
+func (d *decodeState) readFloat(kind reflect.Kind) (float64, error) {
+	if d.flags&flagCompact != 0 && kind == reflect.Float32 {
+		var bits uint32
+		err := binary.Read(d, binary.LittleEndian, &bits)
+		return float64(math.Float32frombits(bits)), err
+	}
+	var val float64
+	err := binary.Read(d, binary.LittleEndian, &val)
+	return val, err
+}
+
+// readBlob reads a length-prefixed byte string into a new slice.
+func (d *decodeState) readBlob() ([]byte, error) {
+	length, err := d.readLen()
+	if err != nil {
+		return nil, err
+	}
+	return d.readBytes(length)
+}
+
+// readBytes reads the next length bytes into a new slice.
+func (d *decodeState) readBytes(length int) ([]byte, error) {
+	if length < 0 {
+		return nil, fmt.Errorf("%w: %d", errdefs.ErrInvalidLength, length)
+	}
+	if length > d.Len() {
+		return nil, io.ErrUnexpectedEOF
+	}
+	blob := make([]byte, length)
+	_, err := io.ReadFull(d, blob)
+	return blob, err
+}
+
+// readBytesAlias reads the next length bytes like readBytes, but returns
+// a slice of the input when aliasing is on. Its capacity ends with the
+// slice, so appending to it cannot overwrite the input.
+func (d *decodeState) readBytesAlias(length int) ([]byte, error) {
+	if !d.alias {
+		return d.readBytes(length)
+	}
+	if length < 0 {
+		return nil, fmt.Errorf("%w: %d", errdefs.ErrInvalidLength, length)
+	}
+	if length > d.Len() {
+		return nil, io.ErrUnexpectedEOF
+	}
+	blob := d.rest()[:length:length]
+	_, err := d.Seek(int64(length), io.SeekCurrent)
+	return blob, err
+}
+
+func (d *decodeState) readLen() (int, error) {
+	if d.flags&flagCompact != 0 {
+		n, err := binary.ReadUvarint(d)
+		if err != nil {
+			return 0, err
+		}
+		if n > math.MaxInt32+1 {
+			return 0, fmt.Errorf("%w: %d", errdefs.ErrInvalidLength, n-1)
+		}
+		return int(n) - 1, nil
+	}
+	var n int32
+	err := binary.Read(d, binary.LittleEndian, &n)
+	return int(n), err
+}
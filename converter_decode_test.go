@@ -0,0 +1,104 @@
+package structo
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/Lucifer07/Structo/errdefs"
+)
+
+type pathItem struct {
+	Name string `structo:"name"`
+}
+
+type pathOrder struct {
+	Items []pathItem
+}
+
+// The path of a DecodeError uses the syntax of DecodeField, so it can be
+// passed back to read the value that failed.
+func TestDecodeErrorPath(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		conv Converter
+		path string
+	}{
+		{"default", NewConverter(), "Items.1.Name"},
+		// the length prefix of the field already shows the truncation
+		{"tagged", NewConverter(WithTaggedFields()), "Items"},
+	} {
+		conv := tc.conv
+		t.Run(tc.name, func(t *testing.T) {
+			in := pathOrder{Items: []pathItem{{Name: "a"}, {Name: "bcdef"}}}
+			bin, err := conv.StructToBinary(in)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var out pathOrder
+			err = conv.BinaryToStruct(bin[:len(bin)-2], &out)
+			var decErr *errdefs.DecodeError
+			if !errors.As(err, &decErr) {
+				t.Fatalf("got %v, want a DecodeError", err)
+			}
+			if decErr.Path != tc.path {
+				t.Fatalf("Path = %q, want %q", decErr.Path, tc.path)
+			}
+
+			if _, err := conv.DecodeField(bin, pathOrder{}, decErr.Path); err != nil {
+				t.Errorf("DecodeField(%q): %v", decErr.Path, err)
+			}
+		})
+	}
+}
+
+type pathIndex struct {
+	ByItem map[pathItem]int64
+	ByName map[string]pathItem
+}
+
+// Map values are addressed by key. A key itself has no path, an error in it
+// is reported at the map.
+func TestDecodeErrorPathMap(t *testing.T) {
+	conv := NewConverter()
+	for _, tc := range []struct {
+		name string
+		in   pathIndex
+		cut  int
+		path string
+		want interface{}
+	}{
+		{"value", pathIndex{ByName: map[string]pathItem{"k": {Name: "bcdef"}}}, 2, "ByName.k.Name", "bcdef"},
+		// the cut lands in the key, before the 8 byte value and the nil ByName
+		{"key", pathIndex{ByItem: map[pathItem]int64{{Name: "bcdef"}: 1}}, 14, "ByItem", map[pathItem]int64{{Name: "bcdef"}: 1}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			bin, err := conv.StructToBinary(tc.in)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var out pathIndex
+			err = conv.BinaryToStruct(bin[:len(bin)-tc.cut], &out)
+			var decErr *errdefs.DecodeError
+			if !errors.As(err, &decErr) {
+				t.Fatalf("got %v, want a DecodeError", err)
+			}
+			if decErr.Path != tc.path {
+				t.Fatalf("Path = %q, want %q", decErr.Path, tc.path)
+			}
+
+			got, err := conv.DecodeField(bin, pathIndex{}, decErr.Path)
+			if err != nil {
+				t.Fatalf("DecodeField(%q): %v", decErr.Path, err)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("DecodeField(%q) = %v, want %v", decErr.Path, got, tc.want)
+			}
+			if _, err := conv.DecodeField(bin, pathIndex{}, "ByName.missing"); !errors.Is(err, errdefs.ErrFieldNotFound) {
+				t.Errorf("DecodeField of a missing key: %v, want ErrFieldNotFound", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,229 @@
+package structo
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"reflect"
+	"unsafe"
+
+	"github.com/Lucifer07/Structo/errdefs"
+)
+
+// encodeState carries the buffer and layout flags of a single encode call.
+type encodeState struct {
+	*bytes.Buffer
+	flags   uint8
+	scratch [binary.MaxVarintLen64]byte
+	// pointers written so far, with flagReferences
+	refs map[refKey]int
+	// nesting depth, and the pointers, maps and slices being written once
+	// it exceeds startDetectingCycles
+	depth int
+	path  map[pathKey]struct{}
+}
+
+func (e *encodeState) encodeValue(v reflect.Value) error {
+	if !v.IsValid() {
+		return errdefs.ErrUnsupportedKind
+	}
+	if codec, ok := lookupTypeCodec(v.Type()); ok {
+		return e.encodeTypeCodec(codec, v)
+	}
+	if kind := marshalKindOf(v.Type()); kind != marshalNone {
+		return e.encodeMarshaler(kind, v)
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr:
+		return e.encodePointer(v)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return e.writeInt(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return e.writeUint(v.Uint())
+	case reflect.Float32, reflect.Float64:
+		return e.writeFloat(v)
+	case reflect.Bool:
+		var b byte
+		if v.Bool() {
+			b = 1
+		}
+		return e.WriteByte(b)
+	case reflect.String:
+		str := v.String()
+		if err := e.writeLen(len(str)); err != nil {
+			return err
+		}
+		_, err := e.WriteString(str)
+		return err
+	case reflect.Struct:
+		if m, ok := e.generatedMarshaler(v); ok {
+			b, err := m.MarshalStructo(e.AvailableBuffer())
+			if err != nil {
+				return err
+			}
+			_, err = e.Write(b)
+			return err
+		}
+		if e.flags&flagTagged != 0 {
+			return e.encodeTaggedStruct(v)
+		}
+		return e.encodeStruct(v)
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return e.writeLen(-1)
+		}
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return e.encodeBytes(v)
+		}
+		if v.Kind() == reflect.Slice {
+			key, err := e.enter(v)
+			if err != nil {
+				return err
+			}
+			defer e.leave(key)
+		}
+		length := v.Len()
+		if err := e.writeLen(length); err != nil {
+			return err
+		}
+		for i := 0; i < length; i++ {
+			if err := e.encodeValue(v.Index(i)); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case reflect.Map:
+		if v.IsNil() {
+			return e.writeLen(-1)
+		}
+		key, err := e.enter(v)
+		if err != nil {
+			return err
+		}
+		defer e.leave(key)
+		if e.flags&flagCanonical != 0 {
+			return e.encodeCanonicalMap(v)
+		}
+		keys := v.MapKeys()
+		if err := e.writeLen(len(keys)); err != nil {
+			return err
+		}
+		for _, key := range keys {
+			if err := e.encodeValue(key); err != nil {
+				return err
+			}
+			if err := e.encodeValue(v.MapIndex(key)); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case reflect.Interface:
+		return e.encodeInterface(v)
+
+	default:
+		return errdefs.ErrUnsupportedKind
+	}
+}
+
+// encodeBytes writes byte slices and arrays as a length and the raw bytes.
+func (e *encodeState) encodeBytes(v reflect.Value) error {
+	if err := e.writeLen(v.Len()); err != nil {
+		return err
+	}
+	if v.Kind() == reflect.Slice {
+		_, err := e.Write(v.Bytes())
+		return err
+	}
+	for i := 0; i < v.Len(); i++ {
+		e.WriteByte(byte(v.Index(i).Uint()))
+	}
+	return nil
+}
+
+// structField returns field i of struct v. Unexported fields of addressable
+// structs are returned writable, so their values can be read through
+// Interface and decoded into.
+func structField(v reflect.Value, i int) reflect.Value {
+	f := v.Field(i)
+	if !f.CanInterface() && f.CanAddr() {
+		f = reflect.NewAt(f.Type(), unsafe.Pointer(f.UnsafeAddr())).Elem()
+	}
+	return f
+}
+
+// addressableStruct returns v itself when it is addressable, otherwise an
+// addressable copy. Structs held in maps or interfaces are not addressable,
+// and structField could not read their unexported fields.
+func addressableStruct(v reflect.Value) reflect.Value {
+	if v.CanAddr() {
+		return v
+	}
+	ptr := reflect.New(v.Type())
+	ptr.Elem().Set(v)
+	return ptr.Elem()
+}
+
+// Primitive writers. The default layout widens integers to 8 bytes and uses
+// int32 lengths, the compact layout (flagCompact) uses varints instead.
+
+func (e *encodeState) writeInt(x int64) error {
+	if e.flags&flagCompact != 0 {
+		n := binary.PutVarint(e.scratch[:], x)
+		_, err := e.Write(e.scratch[:n])
+		return err
+	}
+	return binary.Write(e, binary.LittleEndian, x)
+}
+
+func (e *encodeState) writeUint(x uint64) error {
+	if e.flags&flagCompact != 0 {
+		n := binary.PutUvarint(e.scratch[:], x)
+		_, err := e.Write(e.scratch[:n])
+		return err
+	}
+	return binary.Write(e, binary.LittleEndian, x)
+}
+
+func (e *encodeState) writeFloat(v reflect.Value) error {
+	if e.flags&flagCompact != 0 && v.Kind() == reflect.Float32 {
+		return binary.Write(e, binary.LittleEndian, math.Float32bits(float32(v.Float())))
+	}
+	return binary.Write(e, binary.LittleEndian, v.Float())
+}
+
+// writeLen writes a string, collection or field length. -1 marks nil, the
+// compact layout stores n+1 unsigned, so nil is 0.
+func (e *encodeState) writeLen(n int) error {
+	if e.flags&flagCompact != 0 {
+		return e.writeUint(uint64(n + 1))
+	}
+	return binary.Write(e, binary.LittleEndian, int32(n))
+}
+
+// patchLen writes the length of everything encoded since pos in front of it.
+// In the default layout pos points at a reserved int32, the compact layout
+// has no fixed size and shifts the value to make room for the varint.
+func (e *encodeState) patchLen(pos int) {
+	if e.flags&flagCompact == 0 {
+		binary.LittleEndian.PutUint32(e.Bytes()[pos:], uint32(e.Len()-pos-4))
+		return
+	}
+	length := e.Len() - pos
+	n := binary.PutUvarint(e.scratch[:], uint64(length+1))
+	e.Write(e.scratch[:n])
+	b := e.Bytes()
+	copy(b[pos+n:], b[pos:pos+length])
+	copy(b[pos:], e.scratch[:n])
+}
+
+// reserveLen returns the position to hand to patchLen.
+func (e *encodeState) reserveLen() (int, error) {
+	pos := e.Len()
+	if e.flags&flagCompact != 0 {
+		return pos, nil
+	}
+	return pos, binary.Write(e, binary.LittleEndian, int32(0))
+}
@@ -0,0 +1,43 @@
+package structo
+
+import (
+	"github.com/Lucifer07/Structo/cha"
+)
+
+// Encryptor encrypts the output of the Safe methods, see WithEncryptor.
+// Decrypt must reject data it did not produce rather than return garbage,
+// as the AEADs of the cha package do.
+type Encryptor interface {
+	Encrypt(plaintext []byte) ([]byte, error)
+	Decrypt(ciphertext []byte) ([]byte, error)
+}
+
+// EncryptorWithAD is an Encryptor that can bind ciphertexts to associated
+// data, required by the WithAD methods of Converter.
+type EncryptorWithAD interface {
+	Encryptor
+	EncryptWithAD(plaintext, ad []byte) ([]byte, error)
+	DecryptWithAD(ciphertext, ad []byte) ([]byte, error)
+}
+
+// encryptData adapts cha.EncryptData, whose Encrypt and Decrypt work on
+// strings, to EncryptorWithAD.
+type encryptData struct {
+	*cha.EncryptData
+}
+
+func (e encryptData) Encrypt(plaintext []byte) ([]byte, error) {
+	return e.EncryptBytes(plaintext)
+}
+
+func (e encryptData) Decrypt(ciphertext []byte) ([]byte, error) {
+	return e.DecryptBytes(ciphertext)
+}
+
+func (e encryptData) EncryptWithAD(plaintext, ad []byte) ([]byte, error) {
+	return e.EncryptBytesWithAD(plaintext, ad)
+}
+
+func (e encryptData) DecryptWithAD(ciphertext, ad []byte) ([]byte, error) {
+	return e.DecryptBytesWithAD(ciphertext, ad)
+}
@@ -0,0 +1,160 @@
+package structo
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"testing"
+
+	"github.com/Lucifer07/Structo/cha"
+	"github.com/Lucifer07/Structo/errdefs"
+)
+
+type secretRecord struct {
+	ID    int
+	Token string
+}
+
+func secretKey(b byte) []byte {
+	return bytes.Repeat([]byte{b}, cha.KeySize)
+}
+
+func TestSafeRoundTrip(t *testing.T) {
+	gcm, err := cha.NewAES256GCM(secretKey(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyring := cha.NewKeyring()
+	if err := keyring.Add(1, secretKey(1)); err != nil {
+		t.Fatal(err)
+	}
+
+	in := secretRecord{ID: 7, Token: "s3cret"}
+	for name, conv := range map[string]Converter{
+		"default": NewConverter(),
+		"key":     NewConverter(WithKey(secretKey(1))),
+		"aes-gcm": NewConverter(WithEncryptor(gcm)),
+		"keyring": NewConverter(WithKeyring(keyring)),
+	} {
+		t.Run(name, func(t *testing.T) {
+			encoded, err := conv.EncodeToStringSafe(in)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var out secretRecord
+			if err := conv.DecodeFromStringSafe(encoded, &out); err != nil {
+				t.Fatal(err)
+			}
+			if out != in {
+				t.Errorf("decoded %+v, want %+v", out, in)
+			}
+
+			bound, err := conv.EncodeToStringSafeWithAD(in, []byte("user-7"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			out = secretRecord{}
+			if err := conv.DecodeFromStringSafeWithAD(bound, []byte("user-7"), &out); err != nil {
+				t.Fatal(err)
+			}
+			if out != in {
+				t.Errorf("decoded %+v with ad, want %+v", out, in)
+			}
+		})
+	}
+}
+
+// Tampered strings and strings opened with other associated data fail
+// instead of decoding garbage.
+func TestSafeRejectsTampering(t *testing.T) {
+	conv := NewConverter(WithKey(secretKey(1)))
+	encoded, err := conv.EncodeToStringSafeWithAD(secretRecord{ID: 7}, []byte("user-7"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sealed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, i := range []int{0, 1, len(sealed) / 2, len(sealed) - 1} {
+		tampered := append([]byte(nil), sealed...)
+		tampered[i] ^= 0x80
+		var out secretRecord
+		err := conv.DecodeFromStringSafeWithAD(base64.StdEncoding.EncodeToString(tampered), []byte("user-7"), &out)
+		if !errors.Is(err, errdefs.ErrDecryptionFailed) {
+			t.Errorf("byte %d flipped: %v, want ErrDecryptionFailed", i, err)
+		}
+	}
+
+	for name, decode := range map[string]func(*secretRecord) error{
+		"other ad": func(out *secretRecord) error { return conv.DecodeFromStringSafeWithAD(encoded, []byte("user-8"), out) },
+		"no ad":    func(out *secretRecord) error { return conv.DecodeFromStringSafe(encoded, out) },
+		"other key": func(out *secretRecord) error {
+			return NewConverter(WithKey(secretKey(2))).DecodeFromStringSafeWithAD(encoded, []byte("user-7"), out)
+		},
+	} {
+		if err := decode(&secretRecord{}); !errors.Is(err, errdefs.ErrDecryptionFailed) {
+			t.Errorf("%s: %v, want ErrDecryptionFailed", name, err)
+		}
+	}
+}
+
+// Reencrypt moves data sealed with a retired key to the active one, after
+// which a keyring holding only the new key reads it.
+func TestReencryptToActiveKey(t *testing.T) {
+	keyring := cha.NewKeyring()
+	if err := keyring.Add(1, secretKey(1)); err != nil {
+		t.Fatal(err)
+	}
+	conv := NewConverter(WithKeyring(keyring))
+	in := secretRecord{ID: 7, Token: "s3cret"}
+	old, err := conv.EncodeToStringSafe(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := keyring.Add(2, secretKey(2)); err != nil {
+		t.Fatal(err)
+	}
+	if err := keyring.SetActive(2); err != nil {
+		t.Fatal(err)
+	}
+	moved, err := conv.Reencrypt(old)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sealed, err := base64.StdEncoding.DecodeString(moved)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id := binary.LittleEndian.Uint32(sealed[1:5]); id != 2 {
+		t.Errorf("reencrypted with key %d, want 2", id)
+	}
+
+	current := cha.NewKeyring()
+	if err := current.Add(2, secretKey(2)); err != nil {
+		t.Fatal(err)
+	}
+	var out secretRecord
+	if err := NewConverter(WithKeyring(current)).DecodeFromStringSafe(moved, &out); err != nil {
+		t.Fatal(err)
+	}
+	if out != in {
+		t.Errorf("decoded %+v, want %+v", out, in)
+	}
+	if err := NewConverter(WithKeyring(current)).DecodeFromStringSafe(old, &out); !errors.Is(err, errdefs.ErrUnknownKeyID) {
+		t.Errorf("old string without the old key: %v, want ErrUnknownKeyID", err)
+	}
+}
+
+func TestWithKeyInvalidSize(t *testing.T) {
+	conv := NewConverter(WithKey(make([]byte, 16)))
+	if _, err := conv.EncodeToStringSafe(secretRecord{}); !errors.Is(err, errdefs.ErrInvalidKeySize) {
+		t.Errorf("EncodeToStringSafe: %v, want ErrInvalidKeySize", err)
+	}
+	if err := conv.DecodeFromStringSafe("AAAA", &secretRecord{}); !errors.Is(err, errdefs.ErrInvalidKeySize) {
+		t.Errorf("DecodeFromStringSafe: %v, want ErrInvalidKeySize", err)
+	}
+}
@@ -0,0 +1,340 @@
+package structo
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/Lucifer07/Structo/errdefs"
+)
+
+// fieldInfo describes a struct field as seen by the binary Converter.
+type fieldInfo struct {
+	Index     int
+	Name      string
+	ID        uint32
+	OmitEmpty bool
+}
+
+type structInfo struct {
+	fields []fieldInfo
+	byID   map[uint32]int
+	err    error
+}
+
+type structInfoKey struct {
+	Type         reflect.Type
+	ExportedOnly bool
+}
+
+var structInfoLock sync.RWMutex
+var structInfoMap = make(map[structInfoKey]*structInfo)
+
+// cachedStructInfo parses the `structo` tags of a struct type once and
+// caches the result, the same way deepFields does for the copier. Fields
+// tagged `structo:"-"` and, with flagExportedOnly, unexported fields are
+// left out.
+func cachedStructInfo(reflectType reflect.Type, flags uint8) (*structInfo, error) {
+	key := structInfoKey{Type: reflectType, ExportedOnly: flags&flagExportedOnly != 0}
+	structInfoLock.RLock()
+	cache, ok := structInfoMap[key]
+	structInfoLock.RUnlock()
+	if ok {
+		return cache, cache.err
+	}
+
+	res := &structInfo{byID: map[uint32]int{}}
+	for i := 0; i < reflectType.NumField(); i++ {
+		field := reflectType.Field(i)
+		if key.ExportedOnly && !field.IsExported() {
+			continue
+		}
+		info, skip, err := parseFieldTag(field)
+		if err != nil {
+			res.err = err
+			break
+		}
+		if skip {
+			continue
+		}
+		if prev, ok := res.byID[info.ID]; ok {
+			res.err = fmt.Errorf("%w: %s and %s share id %d", errdefs.ErrDuplicateFieldID, res.fields[prev].Name, info.Name, info.ID)
+			break
+		}
+		info.Index = i
+		res.byID[info.ID] = len(res.fields)
+		res.fields = append(res.fields, info)
+	}
+
+	structInfoLock.Lock()
+	structInfoMap[key] = res
+	structInfoLock.Unlock()
+	return res, res.err
+}
+
+// parseFieldTag reads a `structo:"name,omitempty,id=N"` tag. The optional
+// name replaces the field name, the id defaults to a hash of that name.
+// A tag of "-" skips the field.
+func parseFieldTag(field reflect.StructField) (info fieldInfo, skip bool, err error) {
+	tag := field.Tag.Get("structo")
+	if tag == "-" {
+		return fieldInfo{}, true, nil
+	}
+
+	info.Name = field.Name
+	hasID := false
+	for i, t := range strings.Split(tag, ",") {
+		switch {
+		case i == 0 && !strings.Contains(t, "="):
+			if t != "" {
+				info.Name = t
+			}
+		case t == "omitempty":
+			info.OmitEmpty = true
+		case strings.HasPrefix(t, "id="):
+			id, err := strconv.ParseUint(strings.TrimPrefix(t, "id="), 10, 32)
+			if err != nil {
+				return fieldInfo{}, false, fmt.Errorf("%w: field %s: %q", errdefs.ErrInvalidFieldTag, field.Name, t)
+			}
+			info.ID = uint32(id)
+			hasID = true
+		default:
+			return fieldInfo{}, false, fmt.Errorf("%w: field %s: %q", errdefs.ErrInvalidFieldTag, field.Name, t)
+		}
+	}
+
+	if !hasID {
+		h := fnv.New32a()
+		h.Write([]byte(info.Name))
+		info.ID = h.Sum32()
+	}
+	return info, false, nil
+}
+
+// encodeStruct writes the fields of a struct in declaration order. An
+// omitempty field starts with a presence byte and is left out when zero.
+func (e *encodeState) encodeStruct(v reflect.Value) error {
+	v = addressableStruct(v)
+	info, err := cachedStructInfo(v.Type(), e.flags)
+	if err != nil {
+		return err
+	}
+
+	for _, f := range info.fields {
+		field := structField(v, f.Index)
+		if f.OmitEmpty {
+			if field.IsZero() {
+				if err := e.WriteByte(0); err != nil {
+					return err
+				}
+				continue
+			}
+			if err := e.WriteByte(1); err != nil {
+				return err
+			}
+		}
+		if err := e.encodeValue(field); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// decodeStruct reads a struct written by encodeStruct. Skipped fields keep
+// their value.
+func (d *decodeState) decodeStruct(v reflect.Value) error {
+	info, err := cachedStructInfo(v.Type(), d.flags)
+	if err != nil {
+		return err
+	}
+
+	for _, f := range info.fields {
+		field := structField(v, f.Index)
+		if f.OmitEmpty {
+			present, err := d.ReadByte()
+			if err != nil {
+				return d.at(d.fail(err), v.Type().Field(f.Index).Name)
+			}
+			if present > 1 {
+				return d.at(d.fail(fmt.Errorf("%w: presence byte %d", errdefs.ErrInvalidTypeData, present)), v.Type().Field(f.Index).Name)
+			}
+			if present == 0 {
+				field.Set(reflect.Zero(field.Type()))
+				continue
+			}
+		}
+		if err := d.decodeValue(field); err != nil {
+			return d.at(err, v.Type().Field(f.Index).Name)
+		}
+	}
+	return nil
+}
+
+// encodeTaggedStruct writes a struct in the tagged layout:
+//
+//	length field count
+//	per field: id, length of the value, value
+//
+// Ids are uint32 in the default layout and uvarints in the compact one.
+// Zero omitempty fields are left out.
+func (e *encodeState) encodeTaggedStruct(v reflect.Value) error {
+	v = addressableStruct(v)
+	info, err := cachedStructInfo(v.Type(), e.flags)
+	if err != nil {
+		return err
+	}
+
+	count := len(info.fields)
+	for _, f := range info.fields {
+		if f.OmitEmpty && structField(v, f.Index).IsZero() {
+			count--
+		}
+	}
+	if err := e.writeLen(count); err != nil {
+		return err
+	}
+	for _, f := range info.fields {
+		if f.OmitEmpty && structField(v, f.Index).IsZero() {
+			continue
+		}
+		if err := e.writeFieldID(f.ID); err != nil {
+			return err
+		}
+		pos, err := e.reserveLen()
+		if err != nil {
+			return err
+		}
+		if err := e.encodeValue(structField(v, f.Index)); err != nil {
+			return err
+		}
+		e.patchLen(pos)
+	}
+	return nil
+}
+
+// decodeTaggedStruct reads a struct written by encodeTaggedStruct. Fields
+// with an unknown id are skipped, fields missing from the data are reset to
+// their zero or `default` value. Missing omitempty fields were zero and
+// only get reset.
+func (d *decodeState) decodeTaggedStruct(v reflect.Value) error {
+	info, err := cachedStructInfo(v.Type(), d.flags)
+	if err != nil {
+		return err
+	}
+
+	count, err := d.readLen()
+	if err != nil {
+		return err
+	}
+	if count < 0 {
+		return fmt.Errorf("%w: %d fields", errdefs.ErrInvalidLength, count)
+	}
+	// every field takes at least an id and a length
+	if count > d.Len() {
+		return io.ErrUnexpectedEOF
+	}
+
+	seen := make([]bool, len(info.fields))
+	for i := 0; i < count; i++ {
+		id, err := d.readFieldID()
+		if err != nil {
+			return err
+		}
+		length, err := d.readLen()
+		if err != nil {
+			return err
+		}
+		idx, ok := info.byID[id]
+		if length < 0 || length > d.Len() {
+			err := error(io.ErrUnexpectedEOF)
+			if length < 0 {
+				err = fmt.Errorf("%w: %d", errdefs.ErrInvalidLength, length)
+			}
+			if !ok {
+				return err
+			}
+			return d.at(d.fail(err), v.Type().Field(info.fields[idx].Index).Name)
+		}
+		if !ok {
+			if _, err := d.Seek(int64(length), io.SeekCurrent); err != nil {
+				return err
+			}
+			continue
+		}
+
+		start := d.Len()
+		f := info.fields[idx]
+		if err := d.decodeValue(structField(v, f.Index)); err != nil {
+			return d.at(err, v.Type().Field(f.Index).Name)
+		}
+		if start-d.Len() != length {
+			return fmt.Errorf("%w: field %s changed its type", errdefs.ErrSchemaMismatch, v.Type().Field(f.Index).Name)
+		}
+		seen[idx] = true
+	}
+
+	for idx, f := range info.fields {
+		if seen[idx] {
+			continue
+		}
+		field := v.Field(f.Index)
+		if !field.CanSet() {
+			continue
+		}
+		field.Set(reflect.Zero(field.Type()))
+		if f.OmitEmpty {
+			continue
+		}
+		if err := applyFieldDefaults(field, v.Type().Field(f.Index)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// applyFieldDefaults sets the `default` tag of a field missing from tagged
+// data, and those of its fields if it is a struct. Unlike InjectDefaults it
+// allocates no pointers, nil pointers stay nil.
+func applyFieldDefaults(field reflect.Value, structField reflect.StructField) error {
+	if !structField.IsExported() {
+		return nil
+	}
+
+	if field.Kind() == reflect.Struct {
+		for i := 0; i < field.NumField(); i++ {
+			if err := applyFieldDefaults(field.Field(i), field.Type().Field(i)); err != nil {
+				return err
+			}
+		}
+	}
+	if def := structField.Tag.Get("default"); def != "" && field.IsZero() {
+		return setFieldWithString(field, def)
+	}
+	return nil
+}
+
+func (e *encodeState) writeFieldID(id uint32) error {
+	if e.flags&flagCompact != 0 {
+		return e.writeUint(uint64(id))
+	}
+	return binary.Write(e, binary.LittleEndian, id)
+}
+
+func (d *decodeState) readFieldID() (uint32, error) {
+	if d.flags&flagCompact != 0 {
+		id, err := d.readUint()
+		if id > math.MaxUint32 {
+			return 0, fmt.Errorf("%w: field id %d", errdefs.ErrInvalidFieldTag, id)
+		}
+		return uint32(id), err
+	}
+	var id uint32
+	err := binary.Read(d, binary.LittleEndian, &id)
+	return id, err
+}
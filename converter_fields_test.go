@@ -0,0 +1,112 @@
+package structo
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Lucifer07/Structo/errdefs"
+)
+
+type evolveAddress struct {
+	City string `default:"Jakarta"`
+}
+
+type evolveNode struct {
+	Name string
+	Next *evolveNode
+}
+
+// Fields added after the data was written keep their zero value or their
+// `default` tag: pointers stay nil and recursive types terminate.
+func TestTaggedMissingFields(t *testing.T) {
+	conv := NewConverter(WithTaggedFields())
+
+	// both versions are named record in this package, the tagged layout
+	// fingerprints the package and name only
+	var bin []byte
+	{
+		type record struct {
+			ID int
+		}
+		var err error
+		if bin, err = conv.StructToBinary(record{ID: 7}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	type record struct {
+		ID      int
+		Addr    *evolveAddress
+		Home    evolveAddress
+		Country string `default:"ID"`
+		Node    evolveNode
+		Tags    []string
+	}
+	out := record{Addr: &evolveAddress{City: "stale"}, Tags: []string{"stale"}}
+	if err := conv.BinaryToStruct(bin, &out); err != nil {
+		t.Fatal(err)
+	}
+	if out.ID != 7 {
+		t.Errorf("ID = %d, want 7", out.ID)
+	}
+	if out.Addr != nil {
+		t.Errorf("Addr = %+v, want nil", out.Addr)
+	}
+	if out.Home.City != "Jakarta" {
+		t.Errorf("Home.City = %q, want the default", out.Home.City)
+	}
+	if out.Country != "ID" {
+		t.Errorf("Country = %q, want the default", out.Country)
+	}
+	if out.Node.Next != nil {
+		t.Errorf("Node.Next = %+v, want nil", out.Node.Next)
+	}
+	if out.Tags != nil {
+		t.Errorf("Tags = %v, want nil", out.Tags)
+	}
+
+	// DecodeField agrees with BinaryToStruct on the missing fields
+	for _, path := range []string{"Addr", "Home", "Home.City", "Country", "Node", "Node.Next", "Tags"} {
+		got, err := conv.DecodeField(bin, record{}, path)
+		if err != nil {
+			t.Errorf("DecodeField(%q): %v", path, err)
+			continue
+		}
+		want, err := fieldByPath(reflect.ValueOf(out), strings.Split(path, "."), flagTagged)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("DecodeField(%q) = %#v, BinaryToStruct decoded %#v", path, got, want)
+		}
+	}
+}
+
+// The tagged layout still tells apart same-named types of other packages
+// and unnamed types of a different shape.
+func TestTaggedSchemaMismatch(t *testing.T) {
+	conv := NewConverter(WithTaggedFields())
+
+	type DecodeError struct {
+		Path string
+	}
+	for name, c := range map[string]struct {
+		in, out interface{}
+	}{
+		"other package": {errdefs.DecodeError{Path: "a"}, &DecodeError{}},
+		"unnamed":       {struct{ A int }{1}, &struct{ B string }{}},
+		"unnamed slice": {[]evolveAddress{{}}, &[]evolveNode{}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			bin, err := conv.StructToBinary(c.in)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := conv.BinaryToStruct(bin, c.out); !errors.Is(err, errdefs.ErrSchemaMismatch) {
+				t.Fatalf("BinaryToStruct into %T: %v, want ErrSchemaMismatch", c.out, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package structo
+
+import (
+	"bytes"
+	"reflect"
+
+	"github.com/Lucifer07/Structo/errdefs"
+)
+
+// FormatVersion is the binary format version written by StructToBinary.
+// Generated code records the version it was generated for, methods of an
+// older version are ignored and the Converter falls back to reflection.
+const FormatVersion = formatVersion
+
+// Marshaler is implemented by types with an encoder generated by
+// cmd/structogen. MarshalStructo appends the encoding of the receiver in
+// the default layout to b, byte for byte what reflection would write.
+type Marshaler interface {
+	StructoFormatVersion() int
+	MarshalStructo(b []byte) ([]byte, error)
+}
+
+// Unmarshaler is the decoding counterpart of Marshaler. UnmarshalStructo
+// returns the number of bytes consumed from data.
+type Unmarshaler interface {
+	StructoFormatVersion() int
+	UnmarshalStructo(data []byte) (int, error)
+}
+
+var (
+	marshalerType   = reflect.TypeOf((*Marshaler)(nil)).Elem()
+	unmarshalerType = reflect.TypeOf((*Unmarshaler)(nil)).Elem()
+)
+
+// AppendField appends the default layout encoding of *field to b. Generated
+// code uses it for field types it does not encode by itself.
+func AppendField(b []byte, field interface{}) ([]byte, error) {
+	v := reflect.ValueOf(field)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return nil, errdefs.ErrUnsupportedKind
+	}
+
+	e := &encodeState{Buffer: bytes.NewBuffer(b)}
+	if err := e.encodeValue(v.Elem()); err != nil {
+		return nil, err
+	}
+	return e.Bytes(), nil
+}
+
+// ReadField decodes a value in the default layout from the start of data
+// into *field and returns the number of bytes read.
+func ReadField(data []byte, field interface{}) (int, error) {
+	v := reflect.ValueOf(field)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return 0, errdefs.ErrNotPointerToStruct
+	}
+
+	d := newDecodeState(data, 0)
+	if err := d.decodeValue(v.Elem()); err != nil {
+		// positions are relative to data, the caller reports its own
+		return 0, d.err.Err
+	}
+	return len(data) - d.Len(), nil
+}
+
+// ignoreGenerated makes the Converter encode and decode types with generated
+// methods by reflection, so tests can compare both.
+var ignoreGenerated bool
+
+// generatedMarshaler returns the generated encoder of v, if it has an up to
+// date one. Generated code only covers the default layout.
+func (e *encodeState) generatedMarshaler(v reflect.Value) (Marshaler, bool) {
+	if ignoreGenerated || e.flags != 0 || !v.CanInterface() || !reflect.PointerTo(v.Type()).Implements(marshalerType) {
+		return nil, false
+	}
+	// values of unbounded types may be cyclic, only encodeValue detects that
+	if !boundedType(v.Type()) {
+		return nil, false
+	}
+	if !v.CanAddr() {
+		ptr := reflect.New(v.Type())
+		ptr.Elem().Set(v)
+		v = ptr.Elem()
+	}
+	m := v.Addr().Interface().(Marshaler)
+	return m, m.StructoFormatVersion() == formatVersion
+}
+
+// generatedUnmarshaler is the decoding counterpart of generatedMarshaler.
+// Generated code only checks lengths against the input, it is skipped when
+// DecodeOptions limits are set and for types that nest without bound.
+func (d *decodeState) generatedUnmarshaler(v reflect.Value) (Unmarshaler, bool) {
+	if ignoreGenerated || d.flags != 0 || d.opts.limited() || !v.CanAddr() || !v.CanInterface() || !reflect.PointerTo(v.Type()).Implements(unmarshalerType) {
+		return nil, false
+	}
+	if !boundedType(v.Type()) {
+		return nil, false
+	}
+	u := v.Addr().Interface().(Unmarshaler)
+	return u, u.StructoFormatVersion() == formatVersion
+}
@@ -0,0 +1,61 @@
+package structo_test
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	structo "github.com/Lucifer07/Structo"
+	"github.com/Lucifer07/Structo/internal/gentest"
+)
+
+// Generated methods must produce the bytes of the reflective encoder. The
+// tagged and compact layouts fall back to reflection and must too.
+func TestGeneratedMatchesReflection(t *testing.T) {
+	in := gentest.Sample()
+	body, err := in.MarshalStructo(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, opts := range map[string][]structo.ConverterOption{
+		"default": nil,
+		"tagged":  {structo.WithTaggedFields()},
+		"compact": {structo.WithCompactEncoding()},
+	} {
+		t.Run(name, func(t *testing.T) {
+			conv := structo.NewConverter(opts...)
+			generated, err := conv.StructToBinary(in)
+			if err != nil {
+				t.Fatal(err)
+			}
+			structo.SetIgnoreGenerated(true)
+			reflective, err := conv.StructToBinary(in)
+			structo.SetIgnoreGenerated(false)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !bytes.Equal(generated, reflective) {
+				t.Fatalf("generated methods encode differently:\n\tgenerated  %x\n\treflective %x", generated, reflective)
+			}
+			if name == "default" && !bytes.HasSuffix(generated, body) {
+				t.Errorf("StructToBinary did not use MarshalStructo")
+			}
+
+			// each decodes the other's output
+			for _, ignore := range []bool{false, true} {
+				var out gentest.Fixture
+				structo.SetIgnoreGenerated(ignore)
+				err := conv.BinaryToStruct(generated, &out)
+				structo.SetIgnoreGenerated(false)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !reflect.DeepEqual(out, in) {
+					t.Errorf("decoded %+v, want %+v", out, in)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,176 @@
+package structo
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash"
+	"hash/fnv"
+	"io"
+	"reflect"
+	"strconv"
+	"sync"
+
+	"github.com/Lucifer07/Structo/errdefs"
+)
+
+// Every blob produced by StructToBinary starts with a fixed size header:
+//
+//	magic   [4]byte "STRO"
+//	version uint8   format version of the body
+//	flags   uint8   encoding flags, zero for the default format
+//	schema  uint64  little-endian fingerprint of the encoded type
+const (
+	headerSize    = 14
+	formatVersion = 1
+)
+
+// Header flags select an alternative body layout. Decoding follows the
+// flags of the blob, not the options of the decoding Converter.
+const (
+	// flagTagged writes struct fields with stable IDs, see WithTaggedFields.
+	flagTagged uint8 = 1 << iota
+	// flagCompact writes varints instead of fixed width numbers, see WithCompactEncoding.
+	flagCompact
+	// flagReferences writes repeated pointers as back references, see WithReferences.
+	flagReferences
+	// flagExportedOnly leaves unexported fields out, see WithExportedOnly.
+	flagExportedOnly
+	// flagCanonical writes map entries in a fixed order, see WithCanonical.
+	// It does not change how the body is read.
+	flagCanonical
+
+	knownFlags = flagTagged | flagCompact | flagReferences | flagExportedOnly | flagCanonical
+)
+
+var headerMagic = [4]byte{'S', 'T', 'R', 'O'}
+
+type header struct {
+	version uint8
+	flags   uint8
+	schema  uint64
+}
+
+func writeHeader(buf *bytes.Buffer, h header) {
+	var b [headerSize]byte
+	copy(b[:4], headerMagic[:])
+	b[4] = h.version
+	b[5] = h.flags
+	binary.LittleEndian.PutUint64(b[6:], h.schema)
+	buf.Write(b[:])
+}
+
+func readHeader(data []byte) (header, error) {
+	if len(data) < headerSize || !bytes.Equal(data[:4], headerMagic[:]) {
+		return header{}, errdefs.ErrInvalidHeader
+	}
+
+	h := header{
+		version: data[4],
+		flags:   data[5],
+		schema:  binary.LittleEndian.Uint64(data[6:]),
+	}
+	if h.version != formatVersion {
+		return header{}, fmt.Errorf("%w: %d", errdefs.ErrUnsupportedVersion, h.version)
+	}
+	if h.flags&^knownFlags != 0 {
+		return header{}, fmt.Errorf("%w: unknown flags %#x", errdefs.ErrInvalidHeader, h.flags&^knownFlags)
+	}
+	return h, nil
+}
+
+type schemaKey struct {
+	Type  reflect.Type
+	Flags uint8
+}
+
+var schemaLock sync.RWMutex
+var schemaMap = make(map[schemaKey]uint64)
+
+// schemaHash fingerprints the shape of a type: kinds, the serialized field
+// names and the types they hold. Pointers are transparent, so T and *T hash
+// the same.
+// The tagged layout tolerates changing fields, so there only the type name
+// is fingerprinted.
+func schemaHash(reflectType reflect.Type, flags uint8) uint64 {
+	for reflectType.Kind() == reflect.Ptr {
+		reflectType = reflectType.Elem()
+	}
+
+	key := schemaKey{Type: reflectType, Flags: flags}
+	schemaLock.RLock()
+	cache, ok := schemaMap[key]
+	schemaLock.RUnlock()
+	if ok {
+		return cache
+	}
+
+	h := fnv.New64a()
+	writeSchema(h, reflectType, flags, map[reflect.Type]int{})
+	res := h.Sum64()
+
+	schemaLock.Lock()
+	schemaMap[key] = res
+	schemaLock.Unlock()
+	return res
+}
+
+func writeSchema(h hash.Hash64, t reflect.Type, flags uint8, seen map[reflect.Type]int) {
+	// the tagged layout lets named types gain and lose fields, only their
+	// identity is part of the schema
+	if flags&flagTagged != 0 && t.Name() != "" {
+		if t.PkgPath() != "" {
+			io.WriteString(h, t.PkgPath()+".")
+		}
+		io.WriteString(h, t.Name())
+		return
+	}
+	// recursive types refer back to the first occurrence instead of looping
+	if idx, ok := seen[t]; ok {
+		io.WriteString(h, "@"+strconv.Itoa(idx))
+		return
+	}
+	if t.Name() != "" {
+		seen[t] = len(seen)
+	}
+	if _, ok := lookupTypeCodec(t); ok {
+		io.WriteString(h, "codec "+t.String())
+		return
+	}
+	if marshalKindOf(t) != marshalNone {
+		io.WriteString(h, "marshaler "+t.String())
+		return
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		writeSchema(h, t.Elem(), flags, seen)
+	case reflect.Slice:
+		io.WriteString(h, "[]")
+		writeSchema(h, t.Elem(), flags, seen)
+	case reflect.Array:
+		io.WriteString(h, "["+strconv.Itoa(t.Len())+"]")
+		writeSchema(h, t.Elem(), flags, seen)
+	case reflect.Map:
+		io.WriteString(h, "map[")
+		writeSchema(h, t.Key(), flags, seen)
+		io.WriteString(h, "]")
+		writeSchema(h, t.Elem(), flags, seen)
+	case reflect.Struct:
+		io.WriteString(h, "struct{")
+		// an invalid tag fails the encoding itself, nothing to hash
+		info, _ := cachedStructInfo(t, flags)
+		for _, f := range info.fields {
+			if f.OmitEmpty {
+				io.WriteString(h, f.Name+",omitempty ")
+			} else {
+				io.WriteString(h, f.Name+" ")
+			}
+			writeSchema(h, t.Field(f.Index).Type, flags, seen)
+			io.WriteString(h, ";")
+		}
+		io.WriteString(h, "}")
+	default:
+		io.WriteString(h, t.Kind().String())
+	}
+}
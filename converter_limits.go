@@ -0,0 +1,177 @@
+package structo
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"sync"
+
+	"github.com/Lucifer07/Structo/errdefs"
+)
+
+// DefaultMaxDepth is the nesting depth BinaryToStruct accepts when
+// DecodeOptions.MaxDepth is zero.
+const DefaultMaxDepth = 10000
+
+// DefaultMaxInflatedBytes caps the inflated size of compressed blobs when
+// DecodeOptions.MaxBytes is zero, so a small forged frame cannot inflate to
+// gigabytes. Set MaxBytes to accept larger blobs.
+const DefaultMaxInflatedBytes = 64 << 20
+
+// DecodeOptions limits what the Converter accepts when decoding untrusted
+// input. A zero field leaves that limit off, except MaxDepth which then
+// defaults to DefaultMaxDepth; a negative MaxDepth disables it. Compressed
+// blobs are capped at DefaultMaxInflatedBytes without MaxBytes.
+//
+// Independent of these limits, a length prefix is never trusted beyond the
+// remaining input: every element of a collection takes at least one byte.
+// The exception are structs without encoded fields, which take none; their
+// collections may only be longer than the remaining input if
+// MaxCollectionLen is set.
+type DecodeOptions struct {
+	// MaxBytes caps the size of a blob or stream frame, compressed blobs
+	// are also capped once inflated.
+	MaxBytes int
+	// MaxCollectionLen caps the number of elements of a slice, array or map.
+	MaxCollectionLen int
+	// MaxDepth caps how deeply values may be nested.
+	MaxDepth int
+	// Lenient ignores bytes left over after the decoded value instead of
+	// failing with ErrTrailingData.
+	Lenient bool
+}
+
+// limited reports whether limits beyond the input size are set.
+func (o DecodeOptions) limited() bool {
+	return o.MaxCollectionLen != 0 || o.MaxDepth != 0
+}
+
+func (o DecodeOptions) maxDepth() int {
+	if o.MaxDepth == 0 {
+		return DefaultMaxDepth
+	}
+	return o.MaxDepth
+}
+
+// checkSize rejects input larger than MaxBytes.
+func (o DecodeOptions) checkSize(size uint64) error {
+	if o.MaxBytes > 0 && size > uint64(o.MaxBytes) {
+		return fmt.Errorf("%w: %d bytes, limit %d", errdefs.ErrDataTooLarge, size, o.MaxBytes)
+	}
+	return nil
+}
+
+// checkInflatedSize rejects compressed blobs inflating beyond MaxBytes, or
+// DefaultMaxInflatedBytes if MaxBytes is zero.
+func (o DecodeOptions) checkInflatedSize(size uint64) error {
+	if o.MaxBytes == 0 {
+		o.MaxBytes = DefaultMaxInflatedBytes
+	}
+	return o.checkSize(size)
+}
+
+// enter counts one more level of nesting, leave undoes it.
+func (d *decodeState) enter() error {
+	d.depth++
+	if max := d.opts.maxDepth(); max > 0 && d.depth > max {
+		return fmt.Errorf("%w: limit %d", errdefs.ErrMaxDepthExceeded, max)
+	}
+	return nil
+}
+
+func (d *decodeState) leave() {
+	d.depth--
+}
+
+// checkLen validates a collection length read from the input before
+// anything is allocated for it. elemTypes are the types stored per element.
+func (d *decodeState) checkLen(length int, elemTypes ...reflect.Type) error {
+	if length < 0 {
+		return fmt.Errorf("%w: %d", errdefs.ErrInvalidLength, length)
+	}
+	if max := d.opts.MaxCollectionLen; max > 0 && length > max {
+		return fmt.Errorf("%w: %d elements, limit %d", errdefs.ErrCollectionTooLarge, length, max)
+	}
+	if length <= d.Len() {
+		return nil
+	}
+	if d.opts.MaxCollectionLen == 0 {
+		return io.ErrUnexpectedEOF
+	}
+	for _, t := range elemTypes {
+		if !d.encodesEmpty(t) {
+			return io.ErrUnexpectedEOF
+		}
+	}
+	return nil
+}
+
+// encodesEmpty reports whether values of t take no bytes at all. Only
+// structs in the positional layout whose fields all encode to nothing do.
+func (d *decodeState) encodesEmpty(t reflect.Type) bool {
+	if t.Kind() != reflect.Struct || d.flags&flagTagged != 0 {
+		return false
+	}
+	if _, ok := lookupTypeCodec(t); ok || marshalKindOf(t) != marshalNone {
+		return false
+	}
+	info, err := cachedStructInfo(t, d.flags)
+	if err != nil {
+		return false
+	}
+	for _, f := range info.fields {
+		if f.OmitEmpty || !d.encodesEmpty(t.Field(f.Index).Type) {
+			return false
+		}
+	}
+	return true
+}
+
+var boundedLock sync.RWMutex
+var boundedMap = make(map[reflect.Type]bool)
+
+// boundedType reports whether values of t nest no deeper than the type
+// itself does: t neither refers back to itself nor holds interfaces.
+// Generated code does not track depth, it is only used for such types.
+func boundedType(reflectType reflect.Type) bool {
+	boundedLock.RLock()
+	cache, ok := boundedMap[reflectType]
+	boundedLock.RUnlock()
+	if ok {
+		return cache
+	}
+
+	res := walkBounded(reflectType, map[reflect.Type]bool{})
+
+	boundedLock.Lock()
+	boundedMap[reflectType] = res
+	boundedLock.Unlock()
+	return res
+}
+
+func walkBounded(t reflect.Type, visiting map[reflect.Type]bool) bool {
+	if visiting[t] {
+		return false
+	}
+	if _, ok := lookupTypeCodec(t); ok || marshalKindOf(t) != marshalNone {
+		return true
+	}
+
+	visiting[t] = true
+	defer delete(visiting, t)
+	switch t.Kind() {
+	case reflect.Interface:
+		return false
+	case reflect.Ptr, reflect.Slice, reflect.Array:
+		return walkBounded(t.Elem(), visiting)
+	case reflect.Map:
+		return walkBounded(t.Key(), visiting) && walkBounded(t.Elem(), visiting)
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			if !walkBounded(t.Field(i).Type, visiting) {
+				return false
+			}
+		}
+	}
+	return true
+}
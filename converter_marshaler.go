@@ -0,0 +1,109 @@
+package structo
+
+import (
+	"encoding"
+	"encoding/json"
+	"reflect"
+	"sync"
+)
+
+// marshalKind names the marshaler interface pair a type controls its own
+// representation with. A pair only counts when *T also implements the
+// matching unmarshaler, otherwise the value could not be decoded again.
+type marshalKind uint8
+
+const (
+	marshalNone marshalKind = iota
+	marshalBinary
+	marshalText
+	marshalJSON
+)
+
+var (
+	binaryMarshalerType   = reflect.TypeOf((*encoding.BinaryMarshaler)(nil)).Elem()
+	binaryUnmarshalerType = reflect.TypeOf((*encoding.BinaryUnmarshaler)(nil)).Elem()
+	textMarshalerType     = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	textUnmarshalerType   = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	jsonMarshalerType     = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	jsonUnmarshalerType   = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+)
+
+var marshalKindLock sync.RWMutex
+var marshalKindMap = make(map[reflect.Type]marshalKind)
+
+func marshalKindOf(reflectType reflect.Type) marshalKind {
+	marshalKindLock.RLock()
+	cache, ok := marshalKindMap[reflectType]
+	marshalKindLock.RUnlock()
+	if ok {
+		return cache
+	}
+
+	res := marshalNone
+	if reflectType.Kind() != reflect.Interface {
+		ptrType := reflect.PointerTo(reflectType)
+		switch {
+		case ptrType.Implements(binaryMarshalerType) && ptrType.Implements(binaryUnmarshalerType):
+			res = marshalBinary
+		case ptrType.Implements(textMarshalerType) && ptrType.Implements(textUnmarshalerType):
+			res = marshalText
+		case ptrType.Implements(jsonMarshalerType) && ptrType.Implements(jsonUnmarshalerType):
+			res = marshalJSON
+		}
+	}
+
+	marshalKindLock.Lock()
+	marshalKindMap[reflectType] = res
+	marshalKindLock.Unlock()
+	return res
+}
+
+// encodeMarshaler writes the output of the value's own marshaler as a blob.
+func (e *encodeState) encodeMarshaler(kind marshalKind, v reflect.Value) error {
+	if !v.CanAddr() {
+		ptr := reflect.New(v.Type())
+		ptr.Elem().Set(v)
+		v = ptr.Elem()
+	}
+
+	var (
+		blob []byte
+		err  error
+	)
+	switch kind {
+	case marshalBinary:
+		blob, err = v.Addr().Interface().(encoding.BinaryMarshaler).MarshalBinary()
+	case marshalText:
+		blob, err = v.Addr().Interface().(encoding.TextMarshaler).MarshalText()
+	case marshalJSON:
+		blob, err = v.Addr().Interface().(json.Marshaler).MarshalJSON()
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := e.writeLen(len(blob)); err != nil {
+		return err
+	}
+	_, err = e.Write(blob)
+	return err
+}
+
+// decodeMarshaler hands the blob written by encodeMarshaler to the
+// matching unmarshaler of v.
+func (d *decodeState) decodeMarshaler(kind marshalKind, v reflect.Value) error {
+	blob, err := d.readBlob()
+	if err != nil {
+		return err
+	}
+
+	switch kind {
+	case marshalBinary:
+		return v.Addr().Interface().(encoding.BinaryUnmarshaler).UnmarshalBinary(blob)
+	case marshalText:
+		return v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText(blob)
+	case marshalJSON:
+		return v.Addr().Interface().(json.Unmarshaler).UnmarshalJSON(blob)
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package structo
+
+import (
+	"strconv"
+	"testing"
+)
+
+type textLevel struct{ n int }
+
+func (l textLevel) MarshalText() ([]byte, error) {
+	return []byte(strconv.Itoa(l.n)), nil
+}
+
+func (l *textLevel) UnmarshalText(b []byte) error {
+	n, err := strconv.Atoi(string(b))
+	l.n = n
+	return err
+}
+
+type hiddenMarshaler struct {
+	level textLevel
+}
+
+type hiddenMarshalerHolder struct {
+	ByName map[string]hiddenMarshaler
+	Levels map[string]textLevel
+}
+
+// A marshaler in an unexported field of a map value used to panic in
+// encodeMarshaler.
+func TestMarshalerInUnaddressableStruct(t *testing.T) {
+	in := hiddenMarshalerHolder{
+		ByName: map[string]hiddenMarshaler{"a": {level: textLevel{3}}},
+		Levels: map[string]textLevel{"b": {7}},
+	}
+
+	for name, conv := range map[string]Converter{
+		"default": NewConverter(),
+		"tagged":  NewConverter(WithTaggedFields()),
+	} {
+		t.Run(name, func(t *testing.T) {
+			bin, err := conv.StructToBinary(in)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var out hiddenMarshalerHolder
+			if err := conv.BinaryToStruct(bin, &out); err != nil {
+				t.Fatal(err)
+			}
+			if got := out.ByName["a"].level.n; got != 3 {
+				t.Errorf("ByName[a].level = %d, want 3", got)
+			}
+			if got := out.Levels["b"].n; got != 7 {
+				t.Errorf("Levels[b] = %d, want 7", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package structo
+
+import "github.com/Lucifer07/Structo/cha"
+
+// ConverterOption configures a Converter created by NewConverter.
+type ConverterOption func(c *converterImpl)
+
+// WithTaggedFields writes every struct field together with a stable field ID,
+// taken from a `structo:"id=N"` tag or derived from the field name. Blobs
+// written this way survive adding and removing fields: unknown fields are
+// skipped on decode and missing fields keep their zero or `default` value.
+func WithTaggedFields() ConverterOption {
+	return func(c *converterImpl) {
+		c.flags |= flagTagged
+	}
+}
+
+// WithCompactEncoding writes integers as varints (zigzag encoded when
+// signed), keeps float32 at 4 bytes and prefixes strings and collections
+// with varint lengths. Small numbers then take a single byte instead of 8.
+func WithCompactEncoding() ConverterOption {
+	return func(c *converterImpl) {
+		c.flags |= flagCompact
+	}
+}
+
+// WithReferences preserves pointer identity: a pointer met again while
+// encoding is written as a reference to its first occurrence, so shared
+// pointers decode as shared and cyclic structures can be encoded at all.
+func WithReferences() ConverterOption {
+	return func(c *converterImpl) {
+		c.flags |= flagReferences
+	}
+}
+
+// WithExportedOnly leaves unexported struct fields out of the encoding, the
+// way encoding/json does. By default every field is written.
+func WithExportedOnly() ConverterOption {
+	return func(c *converterImpl) {
+		c.flags |= flagExportedOnly
+	}
+}
+
+// WithCanonical makes the output deterministic: map entries are sorted by
+// the encoding of their keys instead of following Go's random map order,
+// so equal values always encode to the same bytes.
+func WithCanonical() ConverterOption {
+	return func(c *converterImpl) {
+		c.flags |= flagCanonical
+	}
+}
+
+// WithDecodeOptions sets the limits applied when decoding, see DecodeOptions.
+func WithDecodeOptions(opts DecodeOptions) ConverterOption {
+	return func(c *converterImpl) {
+		c.decodeOpts = opts
+	}
+}
+
+// WithCodec switches the Converter to another serialization format, e.g.
+// WithCodec(MsgPack). Every method, the Safe variants and streams included,
+// then reads and writes that format. The layout options only apply to the
+// native Binary codec, and of the DecodeOptions only MaxBytes applies to
+// the others.
+func WithCodec(codec Codec) ConverterOption {
+	return func(c *converterImpl) {
+		if _, ok := codec.(binaryCodec); ok {
+			codec = nil
+		}
+		c.codec = codec
+	}
+}
+
+// WithTextEncoding sets the TextEncoding of EncodeToString, EncodeToStringSafe
+// and their decoding counterparts, e.g. WithTextEncoding(Base64URL) for
+// output safe to put in URLs and cookies. Without it EncodeToString returns
+// the raw binary and EncodeToStringSafe standard base64.
+func WithTextEncoding(enc TextEncoding) ConverterOption {
+	return func(c *converterImpl) {
+		c.text = enc
+	}
+}
+
+// WithCompression compresses the output of StructToBinary, and with it of
+// the string, Safe and stream methods, before any encryption. Blobs shorter
+// than threshold bytes and blobs that do not shrink are left uncompressed.
+// The algorithm is recorded in the output, BinaryToStruct inflates
+// compressed data whatever the Converter's options are.
+func WithCompression(alg Compression, threshold int) ConverterOption {
+	return func(c *converterImpl) {
+		c.compression = alg
+		c.compressThreshold = threshold
+	}
+}
+
+// WithKey encrypts the Safe methods with key instead of a random key only
+// the Converter itself knows, so their output can be stored or decrypted by
+// other processes using the same key. The key must be cha.KeySize bytes,
+// otherwise the Safe methods fail with errdefs.ErrInvalidKeySize.
+func WithKey(key []byte) ConverterOption {
+	return func(c *converterImpl) {
+		e, err := cha.NewEncryptorWithKey(key)
+		c.encryptor, c.encryptorErr = encryptData{e}, err
+	}
+}
+
+// WithEncryptor encrypts the Safe methods with enc instead of the built-in
+// XChaCha20-Poly1305, e.g. cha.NewAES256GCM or a KMS client. The WithAD
+// methods need an EncryptorWithAD and fail with errdefs.ErrNotSupported
+// otherwise.
+func WithEncryptor(enc Encryptor) ConverterOption {
+	return func(c *converterImpl) {
+		c.encryptor, c.encryptorErr = enc, nil
+	}
+}
+
+// WithKeyring encrypts the Safe methods with the active key of keyring and
+// decrypts with whichever key the data names, so keys can be rotated with
+// SetActive while older data stays readable. Reencrypt moves such data to
+// the active key. An empty keyring makes the Safe methods fail with
+// errdefs.ErrUnknownKeyID. It is WithEncryptor(keyring).
+func WithKeyring(keyring *cha.Keyring) ConverterOption {
+	return func(c *converterImpl) {
+		c.encryptor, c.encryptorErr = keyring, nil
+	}
+}
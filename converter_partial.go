@@ -0,0 +1,533 @@
+package structo
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/Lucifer07/Structo/errdefs"
+)
+
+// DecodeField decodes the single value at path from data, a blob encoded
+// from a value of the type of sample, and skips over everything else.
+// Paths use the dot notation of Flatten: struct field names, slice or array
+// indexes and map keys as formatted by fmt.Sprint, e.g. "Address.City",
+// "Tags.2" or "Attrs.color". Keys containing a dot cannot be addressed.
+// Pointers and interface values on the way are followed, an empty path
+// returns the whole value.
+// Blobs written WithReferences are decoded in full, since back references
+// may point into skipped data. Non-binary Codecs are not supported.
+func (c *converterImpl) DecodeField(data []byte, sample interface{}, path string) (interface{}, error) {
+	sampleType := reflect.TypeOf(sample)
+	if sampleType == nil {
+		return nil, errdefs.ErrUnsupportedKind
+	}
+	for sampleType.Kind() == reflect.Ptr {
+		sampleType = sampleType.Elem()
+	}
+	if c.codec != nil {
+		return nil, fmt.Errorf("%w: DecodeField with the %s codec", errdefs.ErrNotSupported, c.codec.Name())
+	}
+
+	if err := c.decodeOpts.checkSize(uint64(len(data))); err != nil {
+		return nil, err
+	}
+	data, err := c.decompress(data)
+	if err != nil {
+		return nil, err
+	}
+	h, err := readHeader(data)
+	if err != nil {
+		return nil, err
+	}
+	if h.schema != schemaHash(sampleType, h.flags) {
+		return nil, errdefs.ErrSchemaMismatch
+	}
+
+	var parts []string
+	if path != "" {
+		parts = strings.Split(path, ".")
+	}
+	if h.flags&flagReferences != 0 {
+		res := reflect.New(sampleType)
+		if err := c.BinaryToStruct(data, res.Interface()); err != nil {
+			return nil, err
+		}
+		return fieldByPath(res.Elem(), parts, h.flags)
+	}
+
+	d := newDecodeState(data[headerSize:], h.flags)
+	d.opts = c.decodeOpts
+	d.base = headerSize
+	return d.decodeField(sampleType, parts, path)
+}
+
+// decodeField skips to the value at parts below a value of type t and
+// decodes it.
+func (d *decodeState) decodeField(t reflect.Type, parts []string, path string) (interface{}, error) {
+	for i, part := range parts {
+		var err error
+		t, err = d.seekField(t, part)
+		var missing *missingField
+		switch {
+		case err == errNoField:
+			return nil, fmt.Errorf("%w: %q", errdefs.ErrFieldNotFound, strings.Join(parts[:i+1], "."))
+		case errors.As(err, &missing):
+			v, err := missing.value()
+			if err != nil {
+				return nil, err
+			}
+			return fieldByPath(v, parts[i+1:], d.flags)
+		case err != nil:
+			if err != error(d.err) {
+				err = d.fail(err)
+			}
+			return nil, d.at(err, strings.Join(parts[:i+1], "."))
+		}
+	}
+
+	v := reflect.New(t).Elem()
+	if err := d.decodeValue(v); err != nil {
+		return nil, d.at(err, path)
+	}
+	return v.Interface(), nil
+}
+
+// errNoField reports a path element that the data cannot contain.
+var errNoField = errors.New("no such field")
+
+// missingField reports a struct field that is not in the data: an empty
+// omitempty field, or a field the tagged layout did not write.
+type missingField struct {
+	field reflect.StructField
+	// defaults are applied like decodeTaggedStruct does
+	defaults bool
+}
+
+func (m *missingField) Error() string {
+	return "field " + m.field.Name + " is not encoded"
+}
+
+// value returns the value BinaryToStruct leaves in the field.
+func (m *missingField) value() (reflect.Value, error) {
+	v := reflect.New(m.field.Type).Elem()
+	if !m.defaults {
+		return v, nil
+	}
+	err := applyFieldDefaults(v, m.field)
+	return v, err
+}
+
+// seekField positions the reader at the value named part inside a value of
+// type t and returns the type of that value. A field that is not in the
+// data is reported as *missingField.
+func (d *decodeState) seekField(t reflect.Type, part string) (reflect.Type, error) {
+	if _, ok := lookupTypeCodec(t); ok {
+		return nil, errNoField
+	}
+	if marshalKindOf(t) != marshalNone {
+		return nil, errNoField
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		tag, err := d.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		if tag != refNew {
+			return nil, errNoField
+		}
+		return d.seekField(t.Elem(), part)
+	case reflect.Interface:
+		elemType, err := d.readInterfaceType()
+		if err != nil {
+			return nil, err
+		}
+		if elemType == nil {
+			return nil, errNoField
+		}
+		return d.seekField(elemType, part)
+	case reflect.Struct:
+		if d.flags&flagTagged != 0 {
+			return d.seekTaggedField(t, part)
+		}
+		return d.seekStructField(t, part)
+	case reflect.Slice, reflect.Array:
+		idx, err := strconv.Atoi(part)
+		if err != nil || idx < 0 {
+			return nil, errNoField
+		}
+		length, err := d.readLen()
+		if err != nil {
+			return nil, err
+		}
+		if length == -1 && t.Kind() == reflect.Slice {
+			return nil, errNoField
+		}
+		if err := d.checkLen(length, t.Elem()); err != nil {
+			return nil, err
+		}
+		if t.Kind() == reflect.Array && length != t.Len() {
+			return nil, fmt.Errorf("%w: array of %d elements, got %d", errdefs.ErrSchemaMismatch, t.Len(), length)
+		}
+		if idx >= length {
+			return nil, errNoField
+		}
+		if t.Elem().Kind() == reflect.Uint8 {
+			return t.Elem(), d.skipBytes(idx)
+		}
+		for i := 0; i < idx; i++ {
+			if err := d.skipValue(t.Elem()); err != nil {
+				return nil, err
+			}
+		}
+		return t.Elem(), nil
+	case reflect.Map:
+		length, err := d.readLen()
+		if err != nil {
+			return nil, err
+		}
+		if length == -1 {
+			return nil, errNoField
+		}
+		if err := d.checkLen(length, t.Key(), t.Elem()); err != nil {
+			return nil, err
+		}
+		for i := 0; i < length; i++ {
+			key := reflect.New(t.Key()).Elem()
+			if err := d.decodeValue(key); err != nil {
+				return nil, d.atKey(err)
+			}
+			if fmt.Sprint(key) == part {
+				return t.Elem(), nil
+			}
+			if err := d.skipValue(t.Elem()); err != nil {
+				return nil, err
+			}
+		}
+		return nil, errNoField
+	}
+	return nil, errNoField
+}
+
+func (d *decodeState) seekStructField(t reflect.Type, name string) (reflect.Type, error) {
+	info, err := cachedStructInfo(t, d.flags)
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range info.fields {
+		sf := t.Field(f.Index)
+		if f.OmitEmpty {
+			present, err := d.ReadByte()
+			if err != nil {
+				return nil, err
+			}
+			if present > 1 {
+				return nil, fmt.Errorf("%w: presence byte %d", errdefs.ErrInvalidTypeData, present)
+			}
+			if present == 0 {
+				if sf.Name == name {
+					return nil, &missingField{field: sf}
+				}
+				continue
+			}
+		}
+		if sf.Name == name {
+			return sf.Type, nil
+		}
+		if err := d.skipValue(sf.Type); err != nil {
+			return nil, err
+		}
+	}
+	return nil, errNoField
+}
+
+func (d *decodeState) seekTaggedField(t reflect.Type, name string) (reflect.Type, error) {
+	info, err := cachedStructInfo(t, d.flags)
+	if err != nil {
+		return nil, err
+	}
+	want := -1
+	for idx, f := range info.fields {
+		if t.Field(f.Index).Name == name {
+			want = idx
+		}
+	}
+	if want == -1 {
+		return nil, errNoField
+	}
+
+	count, err := d.readLen()
+	if err != nil {
+		return nil, err
+	}
+	if count < 0 {
+		return nil, fmt.Errorf("%w: %d fields", errdefs.ErrInvalidLength, count)
+	}
+	for i := 0; i < count; i++ {
+		id, err := d.readFieldID()
+		if err != nil {
+			return nil, err
+		}
+		length, err := d.readLen()
+		if err != nil {
+			return nil, err
+		}
+		if id == info.fields[want].ID {
+			return t.Field(info.fields[want].Index).Type, nil
+		}
+		if err := d.skipBytes(length); err != nil {
+			return nil, err
+		}
+	}
+	f := info.fields[want]
+	return nil, &missingField{field: t.Field(f.Index), defaults: !f.OmitEmpty}
+}
+
+// readInterfaceType reads the registered name of an interface value and
+// returns its type, nil for a nil interface.
+func (d *decodeState) readInterfaceType() (reflect.Type, error) {
+	length, err := d.readLen()
+	if err != nil {
+		return nil, err
+	}
+	if length == -1 {
+		return nil, nil
+	}
+	name, err := d.readBytes(length)
+	if err != nil {
+		return nil, err
+	}
+	t, ok := registeredType(string(name))
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", errdefs.ErrUnknownTypeName, name)
+	}
+	return t, nil
+}
+
+// skipBytes advances the reader by n bytes.
+func (d *decodeState) skipBytes(n int) error {
+	if n < 0 {
+		return fmt.Errorf("%w: %d", errdefs.ErrInvalidLength, n)
+	}
+	if n > d.Len() {
+		return io.ErrUnexpectedEOF
+	}
+	_, err := d.Seek(int64(n), io.SeekCurrent)
+	return err
+}
+
+// skipValue advances the reader over a value of type t without decoding
+// it. It mirrors decodeKind.
+func (d *decodeState) skipValue(t reflect.Type) error {
+	if err := d.enter(); err != nil {
+		return err
+	}
+	defer d.leave()
+
+	if _, ok := lookupTypeCodec(t); ok {
+		return d.skipBlob()
+	}
+	if marshalKindOf(t) != marshalNone {
+		return d.skipBlob()
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		tag, err := d.ReadByte()
+		if err != nil {
+			return err
+		}
+		switch tag {
+		case refNil:
+			return nil
+		case refNew:
+			return d.skipValue(t.Elem())
+		}
+		return fmt.Errorf("%w: tag %d", errdefs.ErrInvalidPointer, tag)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		_, err := d.readInt()
+		return err
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		_, err := d.readUint()
+		return err
+	case reflect.Float32, reflect.Float64:
+		_, err := d.readFloat(t.Kind())
+		return err
+	case reflect.Bool:
+		_, err := d.ReadByte()
+		return err
+	case reflect.String:
+		return d.skipBlob()
+	case reflect.Struct:
+		return d.skipStruct(t)
+	case reflect.Slice, reflect.Array:
+		length, err := d.readLen()
+		if err != nil {
+			return err
+		}
+		if length == -1 && t.Kind() == reflect.Slice {
+			return nil
+		}
+		if err := d.checkLen(length, t.Elem()); err != nil {
+			return err
+		}
+		if t.Elem().Kind() == reflect.Uint8 {
+			return d.skipBytes(length)
+		}
+		for i := 0; i < length; i++ {
+			if err := d.skipValue(t.Elem()); err != nil {
+				return err
+			}
+		}
+		return nil
+	case reflect.Map:
+		length, err := d.readLen()
+		if err != nil {
+			return err
+		}
+		if length == -1 {
+			return nil
+		}
+		if err := d.checkLen(length, t.Key(), t.Elem()); err != nil {
+			return err
+		}
+		for i := 0; i < length; i++ {
+			if err := d.skipValue(t.Key()); err != nil {
+				return err
+			}
+			if err := d.skipValue(t.Elem()); err != nil {
+				return err
+			}
+		}
+		return nil
+	case reflect.Interface:
+		elemType, err := d.readInterfaceType()
+		if err != nil || elemType == nil {
+			return err
+		}
+		return d.skipValue(elemType)
+	}
+	return errdefs.ErrUnsupportedKind
+}
+
+func (d *decodeState) skipStruct(t reflect.Type) error {
+	info, err := cachedStructInfo(t, d.flags)
+	if err != nil {
+		return err
+	}
+	if d.flags&flagTagged != 0 {
+		count, err := d.readLen()
+		if err != nil {
+			return err
+		}
+		if count < 0 {
+			return fmt.Errorf("%w: %d fields", errdefs.ErrInvalidLength, count)
+		}
+		for i := 0; i < count; i++ {
+			if _, err := d.readFieldID(); err != nil {
+				return err
+			}
+			length, err := d.readLen()
+			if err != nil {
+				return err
+			}
+			if err := d.skipBytes(length); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	for _, f := range info.fields {
+		if f.OmitEmpty {
+			present, err := d.ReadByte()
+			if err != nil {
+				return err
+			}
+			if present > 1 {
+				return fmt.Errorf("%w: presence byte %d", errdefs.ErrInvalidTypeData, present)
+			}
+			if present == 0 {
+				continue
+			}
+		}
+		if err := d.skipValue(t.Field(f.Index).Type); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *decodeState) skipBlob() error {
+	length, err := d.readLen()
+	if err != nil {
+		return err
+	}
+	return d.skipBytes(length)
+}
+
+// fieldByPath returns the value at parts below the decoded value v, with
+// the same rules as decodeField.
+func fieldByPath(v reflect.Value, parts []string, flags uint8) (interface{}, error) {
+	for i, part := range parts {
+		for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+			if v.IsNil() {
+				return nil, fmt.Errorf("%w: %q", errdefs.ErrFieldNotFound, strings.Join(parts[:i+1], "."))
+			}
+			v = v.Elem()
+		}
+
+		switch v.Kind() {
+		case reflect.Struct:
+			idx, err := encodedFieldIndex(v.Type(), part, flags)
+			if err != nil {
+				return nil, err
+			}
+			if idx == -1 {
+				return nil, fmt.Errorf("%w: %q", errdefs.ErrFieldNotFound, strings.Join(parts[:i+1], "."))
+			}
+			v = structField(v, idx)
+		case reflect.Slice, reflect.Array:
+			idx, err := strconv.Atoi(part)
+			if err != nil || idx < 0 || idx >= v.Len() {
+				return nil, fmt.Errorf("%w: %q", errdefs.ErrFieldNotFound, strings.Join(parts[:i+1], "."))
+			}
+			v = v.Index(idx)
+		case reflect.Map:
+			found := false
+			iter := v.MapRange()
+			for iter.Next() {
+				if fmt.Sprint(iter.Key()) == part {
+					v, found = iter.Value(), true
+					break
+				}
+			}
+			if !found {
+				return nil, fmt.Errorf("%w: %q", errdefs.ErrFieldNotFound, strings.Join(parts[:i+1], "."))
+			}
+		default:
+			return nil, fmt.Errorf("%w: %q", errdefs.ErrFieldNotFound, strings.Join(parts[:i+1], "."))
+		}
+	}
+	return v.Interface(), nil
+}
+
+// encodedFieldIndex returns the index of the encoded field of t with the Go
+// name name, or -1 if t has no such field or it is not encoded.
+func encodedFieldIndex(t reflect.Type, name string, flags uint8) (int, error) {
+	info, err := cachedStructInfo(t, flags)
+	if err != nil {
+		return -1, err
+	}
+	for _, f := range info.fields {
+		if t.Field(f.Index).Name == name {
+			return f.Index, nil
+		}
+	}
+	return -1, nil
+}
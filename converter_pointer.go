@@ -0,0 +1,124 @@
+package structo
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/Lucifer07/Structo/errdefs"
+)
+
+// Pointers start with a tag byte. The default layout only writes refNil or
+// refNew, with references enabled (flagReferences) a pointer seen before is
+// written as refBack and the index of its first occurrence.
+const (
+	refNil byte = iota
+	refNew
+	refBack
+)
+
+type refKey struct {
+	ptr uintptr
+	typ reflect.Type
+}
+
+// startDetectingCycles is the nesting depth from which encoding tracks the
+// pointers, maps and slices on the current path. Only cyclic values nest
+// that deep in practice, the others never pay for the bookkeeping.
+const startDetectingCycles = 1000
+
+type pathKey struct {
+	ptr uintptr
+	len int
+	typ reflect.Type
+}
+
+// enter fails with errdefs.ErrCycleDetected when v, a non-nil pointer, map
+// or slice, is already being written further up. Each successful call must be
+// paired with leave.
+func (e *encodeState) enter(v reflect.Value) (pathKey, error) {
+	e.depth++
+	if e.depth <= startDetectingCycles {
+		return pathKey{}, nil
+	}
+	key := pathKey{ptr: v.Pointer(), typ: v.Type()}
+	if v.Kind() == reflect.Slice {
+		key.len = v.Len()
+	}
+	if _, ok := e.path[key]; ok {
+		e.depth--
+		return pathKey{}, fmt.Errorf("%w: %s", errdefs.ErrCycleDetected, v.Type())
+	}
+	if e.path == nil {
+		e.path = make(map[pathKey]struct{})
+	}
+	e.path[key] = struct{}{}
+	return key, nil
+}
+
+func (e *encodeState) leave(key pathKey) {
+	e.depth--
+	if key.typ != nil {
+		delete(e.path, key)
+	}
+}
+
+func (e *encodeState) encodePointer(v reflect.Value) error {
+	if v.IsNil() {
+		return e.WriteByte(refNil)
+	}
+	if e.flags&flagReferences != 0 {
+		key := refKey{ptr: v.Pointer(), typ: v.Type()}
+		if id, ok := e.refs[key]; ok {
+			if err := e.WriteByte(refBack); err != nil {
+				return err
+			}
+			return e.writeUint(uint64(id))
+		}
+		// registered before the value is written, so cycles refer back to it
+		e.refs[key] = len(e.refs)
+	} else {
+		key, err := e.enter(v)
+		if err != nil {
+			return err
+		}
+		defer e.leave(key)
+	}
+	if err := e.WriteByte(refNew); err != nil {
+		return err
+	}
+	return e.encodeValue(v.Elem())
+}
+
+func (d *decodeState) decodePointer(v reflect.Value) error {
+	tag, err := d.ReadByte()
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case tag == refNil:
+		v.Set(reflect.Zero(v.Type()))
+		return nil
+	case tag == refNew:
+		ptr := v
+		if v.IsNil() || d.flags&flagReferences != 0 {
+			ptr = reflect.New(v.Type().Elem())
+		}
+		if d.flags&flagReferences != 0 {
+			d.refs = append(d.refs, ptr)
+		}
+		v.Set(ptr)
+		return d.decodeValue(ptr.Elem())
+	case tag == refBack && d.flags&flagReferences != 0:
+		id, err := d.readUint()
+		if err != nil {
+			return err
+		}
+		if id >= uint64(len(d.refs)) || d.refs[id].Type() != v.Type() {
+			return fmt.Errorf("%w: back reference %d to %s", errdefs.ErrInvalidPointer, id, v.Type())
+		}
+		v.Set(d.refs[id])
+		return nil
+	}
+	return fmt.Errorf("%w: tag %d", errdefs.ErrInvalidPointer, tag)
+}
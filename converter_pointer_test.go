@@ -0,0 +1,60 @@
+package structo
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Lucifer07/Structo/errdefs"
+)
+
+type cycleNode struct {
+	Value int
+	Next  *cycleNode
+}
+
+// Cyclic values used to overflow the stack. Without WithReferences they now
+// fail with ErrCycleDetected, with it pointer cycles are written as back
+// references.
+func TestEncodeCycle(t *testing.T) {
+	node := &cycleNode{Value: 1}
+	node.Next = &cycleNode{Value: 2, Next: node}
+	dynamic := map[string]interface{}{"name": "x"}
+	dynamic["self"] = dynamic
+	list := []interface{}{1, nil}
+	list[1] = list
+
+	for name, in := range map[string]interface{}{
+		"pointer": node,
+		"map":     dynamic,
+		"slice":   list,
+	} {
+		t.Run(name, func(t *testing.T) {
+			for layout, opts := range fuzzLayouts {
+				conv := NewConverter(opts...)
+				_, err := conv.StructToBinary(in)
+				if name == "pointer" && conv.(*converterImpl).flags&flagReferences != 0 {
+					if err != nil {
+						t.Errorf("%s: StructToBinary: %v", layout, err)
+					}
+				} else if !errors.Is(err, errdefs.ErrCycleDetected) {
+					t.Errorf("%s: StructToBinary: %v, want ErrCycleDetected", layout, err)
+				}
+			}
+			if _, err := Hash(in); !errors.Is(err, errdefs.ErrCycleDetected) {
+				t.Errorf("Hash: %v, want ErrCycleDetected", err)
+			}
+		})
+	}
+
+	bin, err := NewConverter(WithReferences()).StructToBinary(node)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out cycleNode
+	if err := NewConverter(WithReferences()).BinaryToStruct(bin, &out); err != nil {
+		t.Fatal(err)
+	}
+	if out.Next.Next != &out {
+		t.Errorf("decoded cycle does not point back to the top level value")
+	}
+}
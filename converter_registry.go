@@ -0,0 +1,116 @@
+package structo
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/Lucifer07/Structo/errdefs"
+)
+
+var registryLock sync.RWMutex
+var (
+	nameToType = make(map[string]reflect.Type)
+	typeToName = make(map[reflect.Type]string)
+)
+
+func init() {
+	for _, sample := range []interface{}{
+		false,
+		int(0), int8(0), int16(0), int32(0), int64(0),
+		uint(0), uint8(0), uint16(0), uint32(0), uint64(0),
+		float32(0), float64(0),
+		"", []byte(nil),
+		[]interface{}(nil), map[string]interface{}(nil),
+		time.Time{}, time.Duration(0),
+	} {
+		t := reflect.TypeOf(sample)
+		Register(t.String(), sample)
+	}
+}
+
+// Register records the concrete type of sample under name, so values of
+// that type stored in interface fields can be decoded again. Like
+// gob.Register the name is written to the output, it must be stable and
+// identify the same type wherever the data is read. Basic types, []byte,
+// []interface{}, map[string]interface{}, time.Time and time.Duration are
+// registered under their Go type names.
+//
+// Register panics if name or the type is already registered differently.
+func Register(name string, sample interface{}) {
+	t := reflect.TypeOf(sample)
+	if name == "" || t == nil {
+		panic("structo: Register needs a name and a non-nil sample")
+	}
+
+	registryLock.Lock()
+	defer registryLock.Unlock()
+	if other, ok := nameToType[name]; ok && other != t {
+		panic(fmt.Sprintf("structo: registering duplicate types for %q: %s != %s", name, other, t))
+	}
+	if other, ok := typeToName[t]; ok && other != name {
+		panic(fmt.Sprintf("structo: registering duplicate names for %s: %q != %q", t, other, name))
+	}
+	nameToType[name] = t
+	typeToName[t] = name
+}
+
+// registeredType returns the type registered under name.
+func registeredType(name string) (reflect.Type, bool) {
+	registryLock.RLock()
+	t, ok := nameToType[name]
+	registryLock.RUnlock()
+	return t, ok
+}
+
+func (e *encodeState) encodeInterface(v reflect.Value) error {
+	if v.IsNil() {
+		return e.writeLen(-1)
+	}
+
+	elem := v.Elem()
+	registryLock.RLock()
+	name, ok := typeToName[elem.Type()]
+	registryLock.RUnlock()
+	if !ok {
+		return fmt.Errorf("%w: %s", errdefs.ErrUnregisteredType, elem.Type())
+	}
+
+	if err := e.writeLen(len(name)); err != nil {
+		return err
+	}
+	if _, err := e.WriteString(name); err != nil {
+		return err
+	}
+	return e.encodeValue(elem)
+}
+
+func (d *decodeState) decodeInterface(v reflect.Value) error {
+	length, err := d.readLen()
+	if err != nil {
+		return err
+	}
+	if length == -1 {
+		v.Set(reflect.Zero(v.Type()))
+		return nil
+	}
+	name, err := d.readBytes(length)
+	if err != nil {
+		return err
+	}
+	t, ok := registeredType(string(name))
+	if !ok {
+		return fmt.Errorf("%w: %q", errdefs.ErrUnknownTypeName, name)
+	}
+	if !t.AssignableTo(v.Type()) {
+		return fmt.Errorf("%w: %s does not implement %s", errdefs.ErrSchemaMismatch, t, v.Type())
+	}
+
+	elem := reflect.New(t).Elem()
+	if err := d.decodeValue(elem); err != nil {
+		return err
+	}
+	v.Set(elem)
+	return nil
+}
@@ -0,0 +1,17 @@
+package structo
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Lucifer07/Structo/errdefs"
+)
+
+// uintptr cannot be encoded, so it is not registered for interface fields
+// either.
+func TestUintptrNotRegistered(t *testing.T) {
+	_, err := NewConverter().StructToBinary(struct{ Any interface{} }{Any: uintptr(1)})
+	if !errors.Is(err, errdefs.ErrUnregisteredType) {
+		t.Fatalf("got %v, want ErrUnregisteredType", err)
+	}
+}
@@ -0,0 +1,92 @@
+package structo
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math"
+
+	"github.com/Lucifer07/Structo/errdefs"
+)
+
+// Encoder writes a sequence of structs to an io.Writer. Every value is
+// written as one frame: a uvarint length followed by the StructToBinary blob.
+type Encoder struct {
+	w     io.Writer
+	conv  *converterImpl
+	frame []byte
+}
+
+// NewEncoder returns an Encoder writing to w, configured like NewConverter.
+func NewEncoder(w io.Writer, opts ...ConverterOption) *Encoder {
+	return &Encoder{
+		w:    w,
+		conv: NewConverter(opts...).(*converterImpl),
+	}
+}
+
+// Encode writes the frame of data to the stream.
+func (enc *Encoder) Encode(data interface{}) error {
+	bin, err := enc.conv.StructToBinary(data)
+	if err != nil {
+		return err
+	}
+
+	enc.frame = binary.AppendUvarint(enc.frame[:0], uint64(len(bin)))
+	enc.frame = append(enc.frame, bin...)
+	_, err = enc.w.Write(enc.frame)
+	return err
+}
+
+// Decoder reads a sequence of structs written by an Encoder.
+type Decoder struct {
+	r     byteReader
+	conv  *converterImpl
+	frame bytes.Buffer
+}
+
+type byteReader interface {
+	io.Reader
+	io.ByteReader
+}
+
+// NewDecoder returns a Decoder reading from r, configured like NewConverter.
+// If r does not implement io.ByteReader it is buffered, and the Decoder may
+// read data from r beyond the frames requested.
+func NewDecoder(r io.Reader, opts ...ConverterOption) *Decoder {
+	br, ok := r.(byteReader)
+	if !ok {
+		br = bufio.NewReader(r)
+	}
+	return &Decoder{
+		r:    br,
+		conv: NewConverter(opts...).(*converterImpl),
+	}
+}
+
+// Decode reads the next frame into result. It returns io.EOF once the
+// stream ends between two frames and io.ErrUnexpectedEOF inside a frame.
+func (dec *Decoder) Decode(result interface{}) error {
+	length, err := binary.ReadUvarint(dec.r)
+	if err != nil {
+		return err
+	}
+	if length > math.MaxInt64 {
+		return errdefs.ErrInvalidFrame
+	}
+	if err := dec.conv.decodeOpts.checkSize(length); err != nil {
+		return err
+	}
+
+	// the buffer grows with the data actually read, so a corrupt length
+	// does not allocate more than the stream holds
+	dec.frame.Reset()
+	if _, err := io.CopyN(&dec.frame, dec.r, int64(length)); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return err
+	}
+	return dec.conv.BinaryToStruct(dec.frame.Bytes(), result)
+}
@@ -0,0 +1,316 @@
+package structo
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type fuzzRecord struct {
+	ID     int64
+	Small  uint8
+	Ratio  float64
+	Flag   bool
+	Name   string
+	Blob   []byte
+	Tags   []string
+	Attrs  map[string]int
+	Fixed  [2]int16
+	At     time.Time
+	Any    interface{}
+	Opt    string `structo:",omitempty"`
+	Next   *fuzzRecord
+	hidden int32
+}
+
+// fuzzLayouts are the option sets the fuzz seeds are encoded with.
+var fuzzLayouts = map[string][]ConverterOption{
+	"default":    nil,
+	"tagged":     {WithTaggedFields()},
+	"compact":    {WithCompactEncoding()},
+	"references": {WithReferences()},
+	"all":        {WithTaggedFields(), WithCompactEncoding(), WithReferences()},
+}
+
+// fuzzPaths are read with DecodeField from every input.
+var fuzzPaths = []string{"", "ID", "Name", "Tags.1", "Attrs", "Attrs.x", "Fixed.1", "At", "Any", "Opt", "Next.Name", "Next.Next.Tags.0", "Missing"}
+
+func fuzzSample() *fuzzRecord {
+	head := &fuzzRecord{
+		ID:     -7,
+		Small:  200,
+		Ratio:  1.5,
+		Flag:   true,
+		Name:   "héllo",
+		Blob:   []byte{0, 1, 2},
+		Tags:   []string{"a", "bc"},
+		Attrs:  map[string]int{"x": 1},
+		Fixed:  [2]int16{-1, 1},
+		At:     time.Date(2024, 2, 29, 12, 0, 0, 5, time.UTC),
+		Any:    []interface{}{"s", int64(3)},
+		Opt:    "set",
+		hidden: 9,
+	}
+	head.Next = &fuzzRecord{Name: "next", Tags: []string{}}
+	return head
+}
+
+// Outputs of concurrent calls on one Converter must not share pooled
+// buffers: each still decodes to its own input once all calls are done.
+// Run with -race.
+func TestConcurrentEncode(t *testing.T) {
+	const goroutines, calls = 16, 50
+
+	conv := NewConverter()
+	type result struct {
+		in fuzzRecord
+		// bin is the StructToBinary output, or nil for the
+		// AppendBinary output at appended[start:end]
+		bin        []byte
+		start, end int
+	}
+	results := make([][]result, goroutines)
+	appended := make([][]byte, goroutines)
+
+	var wg sync.WaitGroup
+	for g := 0; g < goroutines; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < calls; i++ {
+				r := result{in: fuzzRecord{
+					ID:   int64(g*calls + i),
+					Name: fmt.Sprintf("g%d-%d", g, i),
+					// vary the size, so buffers of different capacity
+					// move through the pool
+					Tags: strings.Split(strings.Repeat("t,", i%7), ","),
+				}}
+
+				var err error
+				if i%2 == 0 {
+					r.bin, err = conv.StructToBinary(r.in)
+				} else {
+					r.start = len(appended[g])
+					appended[g], err = conv.AppendBinary(appended[g], r.in)
+					r.end = len(appended[g])
+				}
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				results[g] = append(results[g], r)
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	for g, rs := range results {
+		for i, r := range rs {
+			bin := r.bin
+			if bin == nil {
+				bin = appended[g][r.start:r.end]
+			}
+			var out fuzzRecord
+			if err := conv.BinaryToStruct(bin, &out); err != nil {
+				t.Fatalf("goroutine %d call %d: %v", g, i, err)
+			}
+			if !reflect.DeepEqual(out, r.in) {
+				t.Fatalf("goroutine %d call %d: decoded %+v, want %+v", g, i, out, r.in)
+			}
+		}
+	}
+}
+
+// benchRecord is typical of the structs the compact layout targets: small
+// numbers, flags and short strings.
+type benchRecord struct {
+	ID       int64
+	Age      int
+	Score    int32
+	Level    uint8
+	Active   bool
+	Verified bool
+	Name     string
+	Country  string
+	Tags     []string
+	Counts   []int
+}
+
+func benchSample() benchRecord {
+	return benchRecord{
+		ID:       12345,
+		Age:      34,
+		Score:    -120,
+		Level:    3,
+		Active:   true,
+		Verified: false,
+		Name:     "Ada",
+		Country:  "ID",
+		Tags:     []string{"admin", "beta"},
+		Counts:   []int{1, 2, 3, 40, 500},
+	}
+}
+
+// BenchmarkStructToBinaryLayouts compares the default and the compact
+// layout, reporting the encoded size as B/blob.
+func BenchmarkStructToBinaryLayouts(b *testing.B) {
+	for _, layout := range []struct {
+		name string
+		opts []ConverterOption
+	}{
+		{"default", nil},
+		{"compact", []ConverterOption{WithCompactEncoding()}},
+	} {
+		b.Run(layout.name, func(b *testing.B) {
+			conv := NewConverter(layout.opts...)
+			in := benchSample()
+			bin, err := conv.StructToBinary(in)
+			if err != nil {
+				b.Fatal(err)
+			}
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := conv.StructToBinary(in); err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.ReportMetric(float64(len(bin)), "B/blob")
+		})
+	}
+}
+
+// benchDocument is dominated by strings and bytes, where
+// BinaryToStructUnsafe saves the most.
+type benchDocument struct {
+	Title string
+	Body  string
+	Lines []string
+	Raw   []byte
+}
+
+func benchDocumentBlob(b *testing.B) []byte {
+	in := benchDocument{
+		Title: strings.Repeat("title ", 10),
+		Body:  strings.Repeat("lorem ipsum dolor sit amet ", 200),
+		Lines: strings.Split(strings.Repeat("a line of text,", 100), ","),
+		Raw:   make([]byte, 4096),
+	}
+	bin, err := NewConverter().StructToBinary(in)
+	if err != nil {
+		b.Fatal(err)
+	}
+	return bin
+}
+
+func BenchmarkBinaryToStruct(b *testing.B) {
+	benchmarkDecode(b, NewConverter().BinaryToStruct)
+}
+
+func BenchmarkBinaryToStructUnsafe(b *testing.B) {
+	benchmarkDecode(b, NewConverter().BinaryToStructUnsafe)
+}
+
+func benchmarkDecode(b *testing.B, decode func([]byte, interface{}) error) {
+	bin := benchDocumentBlob(b)
+
+	b.ReportAllocs()
+	b.SetBytes(int64(len(bin)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var out benchDocument
+		if err := decode(bin, &out); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// FuzzBinaryToStruct feeds arbitrary data to the decoders of every layout.
+// They must fail cleanly instead of panicking or hanging, and whatever
+// decodes must encode again. The schema in the header is fixed up for
+// fuzzRecord, so mutations reach the body instead of failing the check.
+func FuzzBinaryToStruct(f *testing.F) {
+	seeds, err := filepath.Glob(filepath.Join("testdata", "conformance", "*.bin"))
+	if err != nil {
+		f.Fatal(err)
+	}
+	for _, path := range seeds {
+		blob, err := os.ReadFile(path)
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(blob)
+	}
+	for name, opts := range fuzzLayouts {
+		blob, err := NewConverter(opts...).StructToBinary(fuzzSample())
+		if err != nil {
+			f.Fatalf("%s: %v", name, err)
+		}
+		f.Add(blob)
+	}
+
+	conv := NewConverter(WithDecodeOptions(DecodeOptions{MaxBytes: 1 << 20, MaxCollectionLen: 1 << 12}))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		data = withFuzzSchema(data)
+
+		var out fuzzRecord
+		err := conv.BinaryToStruct(data, &out)
+		var unsafeOut fuzzRecord
+		unsafeErr := conv.BinaryToStructUnsafe(data, &unsafeOut)
+		if (err == nil) != (unsafeErr == nil) {
+			t.Fatalf("BinaryToStruct: %v, BinaryToStructUnsafe: %v", err, unsafeErr)
+		}
+		for _, path := range fuzzPaths {
+			conv.DecodeField(data, fuzzRecord{}, path)
+		}
+		if err != nil {
+			return
+		}
+
+		h, _ := readHeader(data)
+		again, err := NewConverter(flagOptions(h.flags)...).StructToBinary(&out)
+		if err != nil {
+			t.Fatalf("encoding the decoded value: %v", err)
+		}
+		var res fuzzRecord
+		if err := conv.BinaryToStruct(again, &res); err != nil {
+			t.Fatalf("decoding the encoded value: %v", err)
+		}
+	})
+}
+
+// withFuzzSchema returns data with the schema hash of its header replaced
+// by the one of fuzzRecord.
+func withFuzzSchema(data []byte) []byte {
+	h, err := readHeader(data)
+	if err != nil {
+		return data
+	}
+	data = append([]byte(nil), data...)
+	binary.LittleEndian.PutUint64(data[6:], schemaHash(reflect.TypeOf(fuzzRecord{}), h.flags))
+	return data
+}
+
+// flagOptions returns the options writing a header with flags.
+func flagOptions(flags uint8) []ConverterOption {
+	var opts []ConverterOption
+	for flag, opt := range map[uint8]ConverterOption{
+		flagTagged:       WithTaggedFields(),
+		flagCompact:      WithCompactEncoding(),
+		flagReferences:   WithReferences(),
+		flagExportedOnly: WithExportedOnly(),
+		flagCanonical:    WithCanonical(),
+	} {
+		if flags&flag != 0 {
+			opts = append(opts, opt)
+		}
+	}
+	return opts
+}
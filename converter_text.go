@@ -0,0 +1,43 @@
+package structo
+
+import (
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/hex"
+)
+
+// TextEncoding turns the blobs of a Converter into text and back, see
+// WithTextEncoding. *base64.Encoding and *base32.Encoding implement it.
+type TextEncoding interface {
+	EncodeToString(src []byte) string
+	DecodeString(s string) ([]byte, error)
+}
+
+var (
+	// RawText returns the blob as is. It is the default of EncodeToString,
+	// the result is not safe to embed in URLs, headers or JSON.
+	RawText TextEncoding = rawText{}
+	// Base64Std is standard padded base64 (RFC 4648), the default of
+	// EncodeToStringSafe.
+	Base64Std TextEncoding = base64.StdEncoding
+	// Base64URL is the unpadded URL and file name safe base64 alphabet,
+	// suited for URLs, headers and cookies.
+	Base64URL TextEncoding = base64.RawURLEncoding
+	// Base32 is standard padded base32 (RFC 4648), e.g. for case
+	// insensitive identifiers.
+	Base32 TextEncoding = base32.StdEncoding
+	// Hex is lower case hexadecimal.
+	Hex TextEncoding = hexText{}
+)
+
+type rawText struct{}
+
+func (rawText) EncodeToString(src []byte) string { return string(src) }
+
+func (rawText) DecodeString(s string) ([]byte, error) { return []byte(s), nil }
+
+type hexText struct{}
+
+func (hexText) EncodeToString(src []byte) string { return hex.EncodeToString(src) }
+
+func (hexText) DecodeString(s string) ([]byte, error) { return hex.DecodeString(s) }
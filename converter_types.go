@@ -0,0 +1,191 @@
+package structo
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/big"
+	"net"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/Lucifer07/Structo/errdefs"
+)
+
+// TypeCodec makes the binary Converter write a type as an opaque,
+// length-prefixed blob instead of walking its fields with reflection.
+type TypeCodec struct {
+	Type   interface{}
+	Encode func(v interface{}) ([]byte, error)
+	// Decode returns a value of Type or a pointer to one.
+	Decode func(data []byte) (interface{}, error)
+}
+
+var (
+	// TimeCodec writes time.Time as Unix seconds, nanoseconds, zone offset
+	// and location name. The monotonic clock reading is dropped.
+	TimeCodec = TypeCodec{
+		Type:   time.Time{},
+		Encode: encodeTime,
+		Decode: decodeTime,
+	}
+
+	// BigIntCodec writes big.Int as a sign byte and its big-endian magnitude.
+	BigIntCodec = TypeCodec{
+		Type: big.Int{},
+		Encode: func(v interface{}) ([]byte, error) {
+			x := v.(big.Int)
+			sign := byte(0)
+			if x.Sign() < 0 {
+				sign = 1
+			}
+			return append([]byte{sign}, x.Bytes()...), nil
+		},
+		Decode: func(data []byte) (interface{}, error) {
+			if len(data) == 0 {
+				return nil, fmt.Errorf("%w: empty big.Int", errdefs.ErrInvalidTypeData)
+			}
+			x := new(big.Int).SetBytes(data[1:])
+			if data[0] == 1 {
+				x.Neg(x)
+			}
+			return x, nil
+		},
+	}
+
+	// UUIDCodec writes uuid.UUID as its 16 raw bytes.
+	UUIDCodec = TypeCodec{
+		Type: uuid.UUID{},
+		Encode: func(v interface{}) ([]byte, error) {
+			id := v.(uuid.UUID)
+			return id[:], nil
+		},
+		Decode: func(data []byte) (interface{}, error) {
+			return uuid.FromBytes(data)
+		},
+	}
+
+	// IPCodec writes net.IP as its raw 4 or 16 bytes.
+	IPCodec = TypeCodec{
+		Type: net.IP{},
+		Encode: func(v interface{}) ([]byte, error) {
+			return v.(net.IP), nil
+		},
+		Decode: func(data []byte) (interface{}, error) {
+			return net.IP(append([]byte(nil), data...)), nil
+		},
+	}
+
+	// DefaultTypeCodecs are registered on start up. time.Duration needs no
+	// codec, it is written like any other int64.
+	DefaultTypeCodecs = []TypeCodec{
+		TimeCodec,
+		BigIntCodec,
+		UUIDCodec,
+		IPCodec,
+	}
+)
+
+var typeCodecLock sync.RWMutex
+var typeCodecMap = make(map[reflect.Type]TypeCodec)
+
+func init() {
+	for _, codec := range DefaultTypeCodecs {
+		RegisterTypeCodec(codec)
+	}
+}
+
+// RegisterTypeCodec registers codec for its Type, replacing an earlier
+// registration. Register codecs before encoding: blobs written without the
+// codec cannot be read with it and vice versa.
+func RegisterTypeCodec(codec TypeCodec) {
+	t := reflect.TypeOf(codec.Type)
+
+	typeCodecLock.Lock()
+	typeCodecMap[t] = codec
+	typeCodecLock.Unlock()
+
+	// codec types are fingerprinted differently, drop stale hashes
+	schemaLock.Lock()
+	schemaMap = make(map[schemaKey]uint64)
+	schemaLock.Unlock()
+}
+
+func lookupTypeCodec(t reflect.Type) (TypeCodec, bool) {
+	typeCodecLock.RLock()
+	codec, ok := typeCodecMap[t]
+	typeCodecLock.RUnlock()
+	return codec, ok
+}
+
+func (e *encodeState) encodeTypeCodec(codec TypeCodec, v reflect.Value) error {
+	blob, err := codec.Encode(v.Interface())
+	if err != nil {
+		return err
+	}
+	if err := e.writeLen(len(blob)); err != nil {
+		return err
+	}
+	_, err = e.Write(blob)
+	return err
+}
+
+func (d *decodeState) decodeTypeCodec(codec TypeCodec, v reflect.Value) error {
+	blob, err := d.readBlob()
+	if err != nil {
+		return err
+	}
+	res, err := codec.Decode(blob)
+	if err != nil {
+		return err
+	}
+
+	rv := reflect.ValueOf(res)
+	if rv.Kind() == reflect.Ptr && rv.Type().Elem() == v.Type() {
+		rv = rv.Elem()
+	}
+	if !rv.IsValid() || rv.Type() != v.Type() {
+		return fmt.Errorf("%w: codec of %s returned %T", errdefs.ErrInvalidTypeData, v.Type(), res)
+	}
+	v.Set(rv)
+	return nil
+}
+
+func encodeTime(v interface{}) ([]byte, error) {
+	t := v.(time.Time)
+	_, offset := t.Zone()
+
+	b := make([]byte, 16, 16+len(t.Location().String()))
+	binary.LittleEndian.PutUint64(b, uint64(t.Unix()))
+	binary.LittleEndian.PutUint32(b[8:], uint32(t.Nanosecond()))
+	binary.LittleEndian.PutUint32(b[12:], uint32(int32(offset)))
+	return append(b, t.Location().String()...), nil
+}
+
+func decodeTime(data []byte) (interface{}, error) {
+	if len(data) < 16 {
+		return nil, fmt.Errorf("%w: time.Time of %d bytes", errdefs.ErrInvalidTypeData, len(data))
+	}
+	sec := int64(binary.LittleEndian.Uint64(data))
+	nsec := int64(binary.LittleEndian.Uint32(data[8:]))
+	offset := int(int32(binary.LittleEndian.Uint32(data[12:])))
+	name := string(data[16:])
+
+	t := time.Unix(sec, nsec)
+	switch name {
+	case "UTC":
+		return t.UTC(), nil
+	case "Local":
+		return t.Local(), nil
+	}
+	// prefer the real location to keep its DST rules, unless this machine
+	// disagrees about the offset
+	if loc, err := time.LoadLocation(name); err == nil {
+		if _, off := t.In(loc).Zone(); off == offset {
+			return t.In(loc), nil
+		}
+	}
+	return t.In(time.FixedZone(name, offset)), nil
+}
@@ -0,0 +1,74 @@
+package structo
+
+import (
+	"math/big"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type hiddenWellKnown struct {
+	ip   net.IP
+	at   time.Time
+	n    big.Int
+	id   uuid.UUID
+	Name string
+}
+
+type hiddenWellKnownHolder struct {
+	ByID map[int]hiddenWellKnown
+	Any  interface{}
+}
+
+func init() {
+	Register("structo.hiddenWellKnown", hiddenWellKnown{})
+}
+
+// Well-known types in unexported fields of structs that cannot be
+// addressed, map values and interface values, used to panic.
+func TestWellKnownTypesInUnaddressableStructs(t *testing.T) {
+	v := hiddenWellKnown{
+		ip:   net.IPv4(192, 0, 2, 1),
+		at:   time.Unix(1700000000, 5).UTC(),
+		id:   uuid.MustParse("0190b7a6-5d2c-7f3e-9a41-6c1f2e3d4b5a"),
+		Name: "x",
+	}
+	v.n.SetInt64(-42)
+	in := hiddenWellKnownHolder{
+		ByID: map[int]hiddenWellKnown{1: v},
+		Any:  v,
+	}
+
+	for name, conv := range map[string]Converter{
+		"default": NewConverter(),
+		"tagged":  NewConverter(WithTaggedFields()),
+		"compact": NewConverter(WithCompactEncoding()),
+	} {
+		t.Run(name, func(t *testing.T) {
+			bin, err := conv.StructToBinary(in)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var out hiddenWellKnownHolder
+			if err := conv.BinaryToStruct(bin, &out); err != nil {
+				t.Fatal(err)
+			}
+			checkHiddenWellKnown(t, "map value", out.ByID[1], v)
+			got, ok := out.Any.(hiddenWellKnown)
+			if !ok {
+				t.Fatalf("interface value decoded as %T", out.Any)
+			}
+			checkHiddenWellKnown(t, "interface value", got, v)
+		})
+	}
+}
+
+func checkHiddenWellKnown(t *testing.T, where string, got, want hiddenWellKnown) {
+	t.Helper()
+	if !got.ip.Equal(want.ip) || !got.at.Equal(want.at) || got.n.Cmp(&want.n) != 0 || got.id != want.id || got.Name != want.Name {
+		t.Errorf("%s: got %+v, want %+v", where, got, want)
+	}
+}
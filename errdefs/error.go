@@ -1,6 +1,10 @@
 package errdefs
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+	"io"
+)
 
 var (
 	ErrInvalidCopyDestination        = errors.New("copy destination must be non-nil and addressable")
@@ -13,4 +17,54 @@ var (
 	ErrFieldNotSettable              = errors.New("cannot set value to field")
 	ErrMismatchedStructTypes         = errors.New("structs must be of the same type")
 	ErrNotPointerToStruct            = errors.New("input must be a pointer to a struct")
+	ErrInvalidHeader                 = errors.New("binary data has no valid structo header")
+	ErrUnsupportedVersion            = errors.New("unsupported structo format version")
+	ErrSchemaMismatch                = errors.New("binary data was encoded from a different type")
+	ErrInvalidFieldTag               = errors.New("invalid structo field tag")
+	ErrDuplicateFieldID              = errors.New("duplicate structo field id")
+	ErrInvalidFrame                  = errors.New("invalid structo stream frame")
+	ErrInvalidTypeData               = errors.New("invalid encoded data for type")
+	ErrInvalidPointer                = errors.New("invalid pointer encoding")
+	ErrUnregisteredType              = errors.New("type of interface value is not registered")
+	ErrUnknownTypeName               = errors.New("unknown registered type name")
+	ErrInvalidLength                 = errors.New("invalid length prefix")
+	ErrDataTooLarge                  = errors.New("binary data exceeds the size limit")
+	ErrCollectionTooLarge            = errors.New("collection exceeds the length limit")
+	ErrMaxDepthExceeded              = errors.New("binary data exceeds the nesting depth limit")
+	ErrInvalidTextEncoding           = errors.New("string is not valid for the text encoding")
+	ErrInvalidCompression            = errors.New("invalid compressed structo data")
+	ErrFieldNotFound                 = errors.New("field path not found in binary data")
+	ErrInvalidKeySize                = errors.New("invalid encryption key size")
+	ErrDecryptionFailed              = errors.New("decryption failed: data was tampered with or uses another key")
+	ErrUnknownKeyID                  = errors.New("unknown encryption key id")
+	ErrDuplicateKeyID                = errors.New("duplicate encryption key id")
+	ErrCycleDetected                 = errors.New("cycle detected; use WithReferences")
+)
+
+var (
+	ErrTruncated    = fmt.Errorf("binary data is truncated: %w", io.ErrUnexpectedEOF)
+	ErrTrailingData = errors.New("binary data has trailing bytes")
 )
+
+// DecodeError reports where decoding binary data stopped.
+type DecodeError struct {
+	// Offset is the byte offset into the data.
+	Offset int
+	// Path leads to the value being decoded in the syntax of Flatten and
+	// DecodeField: Go field names, slice and array indexes and map keys
+	// joined by dots, e.g. "Address.City" or "Tags.2". An error in a map key
+	// leads to the map itself. It is empty for the top level value.
+	Path string
+	Err  error
+}
+
+func (e *DecodeError) Error() string {
+	if e.Path == "" {
+		return fmt.Sprintf("decoding at offset %d: %v", e.Offset, e.Err)
+	}
+	return fmt.Sprintf("decoding %s at offset %d: %v", e.Path, e.Offset, e.Err)
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
@@ -1,3 +1,5 @@
+//go:generate go run github.com/Lucifer07/Structo/cmd/structogen -type=User,Address,Metadata
+
 package main
 
 import (
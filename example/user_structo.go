@@ -0,0 +1,212 @@
+// Code generated by structogen. DO NOT EDIT.
+
+package main
+
+import (
+	"encoding/binary"
+	"io"
+	"math"
+
+	structo "github.com/Lucifer07/Structo"
+	"github.com/Lucifer07/Structo/errdefs"
+)
+
+// StructoFormatVersion reports the binary format the methods of User were generated for.
+func (*User) StructoFormatVersion() int { return 1 }
+
+// MarshalStructo appends the binary encoding of x to b.
+func (x *User) MarshalStructo(b []byte) ([]byte, error) {
+	var err error
+	b = binary.LittleEndian.AppendUint64(b, uint64(int64(x.ID)))
+	b = binary.LittleEndian.AppendUint32(b, uint32(len(x.Name)))
+	b = append(b, x.Name...)
+	if b, err = structo.AppendField(b, &x.Email); err != nil {
+		return nil, err
+	}
+	if x.Tags == nil {
+		b = binary.LittleEndian.AppendUint32(b, math.MaxUint32)
+	} else {
+		b = binary.LittleEndian.AppendUint32(b, uint32(len(x.Tags)))
+		for i := range x.Tags {
+			b = binary.LittleEndian.AppendUint32(b, uint32(len(x.Tags[i])))
+			b = append(b, x.Tags[i]...)
+		}
+	}
+	if b, err = x.Metadata.MarshalStructo(b); err != nil {
+		return nil, err
+	}
+	if b, err = structo.AppendField(b, &x.Address); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// UnmarshalStructo decodes x from the start of data and returns the number of bytes read.
+func (x *User) UnmarshalStructo(data []byte) (int, error) {
+	var n int
+	if len(data)-n < 8 {
+		return 0, io.ErrUnexpectedEOF
+	}
+	x.ID = int(int64(binary.LittleEndian.Uint64(data[n:])))
+	n += 8
+	{
+		if len(data)-n < 4 {
+			return 0, io.ErrUnexpectedEOF
+		}
+		l := int(int32(binary.LittleEndian.Uint32(data[n:])))
+		n += 4
+		if l < 0 {
+			return 0, errdefs.ErrInvalidLength
+		}
+		if len(data)-n < l {
+			return 0, io.ErrUnexpectedEOF
+		}
+		x.Name = string(data[n : n+l])
+		n += l
+	}
+	{
+		m, err := structo.ReadField(data[n:], &x.Email)
+		if err != nil {
+			return 0, err
+		}
+		n += m
+	}
+	{
+		if len(data)-n < 4 {
+			return 0, io.ErrUnexpectedEOF
+		}
+		l := int(int32(binary.LittleEndian.Uint32(data[n:])))
+		n += 4
+		switch {
+		case l == -1:
+			x.Tags = nil
+		case l < 0:
+			return 0, errdefs.ErrInvalidLength
+		case l > len(data)-n:
+			return 0, io.ErrUnexpectedEOF
+		default:
+			x.Tags = make([]string, l)
+			for i := range x.Tags {
+				{
+					if len(data)-n < 4 {
+						return 0, io.ErrUnexpectedEOF
+					}
+					l := int(int32(binary.LittleEndian.Uint32(data[n:])))
+					n += 4
+					if l < 0 {
+						return 0, errdefs.ErrInvalidLength
+					}
+					if len(data)-n < l {
+						return 0, io.ErrUnexpectedEOF
+					}
+					x.Tags[i] = string(data[n : n+l])
+					n += l
+				}
+			}
+		}
+	}
+	{
+		m, err := x.Metadata.UnmarshalStructo(data[n:])
+		if err != nil {
+			return 0, err
+		}
+		n += m
+	}
+	{
+		m, err := structo.ReadField(data[n:], &x.Address)
+		if err != nil {
+			return 0, err
+		}
+		n += m
+	}
+	return n, nil
+}
+
+// StructoFormatVersion reports the binary format the methods of Address were generated for.
+func (*Address) StructoFormatVersion() int { return 1 }
+
+// MarshalStructo appends the binary encoding of x to b.
+func (x *Address) MarshalStructo(b []byte) ([]byte, error) {
+	b = binary.LittleEndian.AppendUint32(b, uint32(len(x.City)))
+	b = append(b, x.City...)
+	b = binary.LittleEndian.AppendUint32(b, uint32(len(x.ZipCode)))
+	b = append(b, x.ZipCode...)
+	return b, nil
+}
+
+// UnmarshalStructo decodes x from the start of data and returns the number of bytes read.
+func (x *Address) UnmarshalStructo(data []byte) (int, error) {
+	var n int
+	{
+		if len(data)-n < 4 {
+			return 0, io.ErrUnexpectedEOF
+		}
+		l := int(int32(binary.LittleEndian.Uint32(data[n:])))
+		n += 4
+		if l < 0 {
+			return 0, errdefs.ErrInvalidLength
+		}
+		if len(data)-n < l {
+			return 0, io.ErrUnexpectedEOF
+		}
+		x.City = string(data[n : n+l])
+		n += l
+	}
+	{
+		if len(data)-n < 4 {
+			return 0, io.ErrUnexpectedEOF
+		}
+		l := int(int32(binary.LittleEndian.Uint32(data[n:])))
+		n += 4
+		if l < 0 {
+			return 0, errdefs.ErrInvalidLength
+		}
+		if len(data)-n < l {
+			return 0, io.ErrUnexpectedEOF
+		}
+		x.ZipCode = string(data[n : n+l])
+		n += l
+	}
+	return n, nil
+}
+
+// StructoFormatVersion reports the binary format the methods of Metadata were generated for.
+func (*Metadata) StructoFormatVersion() int { return 1 }
+
+// MarshalStructo appends the binary encoding of x to b.
+func (x *Metadata) MarshalStructo(b []byte) ([]byte, error) {
+	if x.Active {
+		b = append(b, 1)
+	} else {
+		b = append(b, 0)
+	}
+	b = binary.LittleEndian.AppendUint32(b, uint32(len(x.Version)))
+	b = append(b, x.Version...)
+	return b, nil
+}
+
+// UnmarshalStructo decodes x from the start of data and returns the number of bytes read.
+func (x *Metadata) UnmarshalStructo(data []byte) (int, error) {
+	var n int
+	if len(data)-n < 1 {
+		return 0, io.ErrUnexpectedEOF
+	}
+	x.Active = data[n] == 1
+	n++
+	{
+		if len(data)-n < 4 {
+			return 0, io.ErrUnexpectedEOF
+		}
+		l := int(int32(binary.LittleEndian.Uint32(data[n:])))
+		n += 4
+		if l < 0 {
+			return 0, errdefs.ErrInvalidLength
+		}
+		if len(data)-n < l {
+			return 0, io.ErrUnexpectedEOF
+		}
+		x.Version = string(data[n : n+l])
+		n += l
+	}
+	return n, nil
+}
@@ -0,0 +1,7 @@
+package structo
+
+// SetIgnoreGenerated switches the use of generated methods off or on again,
+// see ignoreGenerated.
+func SetIgnoreGenerated(ignore bool) {
+	ignoreGenerated = ignore
+}
@@ -5,11 +5,15 @@ go 1.23.0
 toolchain go1.23.7
 
 require (
+	github.com/fxamacker/cbor/v2 v2.9.0
+	github.com/google/uuid v1.6.0
+	github.com/vmihailenco/msgpack/v5 v5.4.1
 	golang.org/x/crypto v0.36.0
 	google.golang.org/protobuf v1.36.5
 )
 
 require (
-	github.com/google/uuid v1.6.0 // indirect
+	github.com/vmihailenco/tagparser/v2 v2.0.0 // indirect
+	github.com/x448/float16 v0.8.4 // indirect
 	golang.org/x/sys v0.31.0 // indirect
 )
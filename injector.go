@@ -20,46 +20,55 @@ func InjectDefaults(ptr interface{}) error {
 		return errdefs.ErrUnsupportedKind
 	}
 
-	return injectDefaultsRecursive(v)
+	return injectDefaultsRecursive(v, map[reflect.Type]bool{})
 }
 
-func injectDefaultsRecursive(v reflect.Value) error {
+// path holds the struct types being injected, nil pointers to them are not
+// allocated again so recursive types terminate.
+func injectDefaultsRecursive(v reflect.Value, path map[reflect.Type]bool) error {
 	t := v.Type()
+	path[t] = true
+	defer delete(path, t)
 
 	for i := 0; i < v.NumField(); i++ {
-		field := v.Field(i)
-		structField := t.Field(i)
-
-		if !structField.IsExported() {
-			continue
+		if err := injectFieldDefaults(v.Field(i), t.Field(i), path); err != nil {
+			return err
 		}
+	}
+	return nil
+}
 
-		// Handle nested struct or pointer to struct
-		switch field.Kind() {
-		case reflect.Struct:
-			err := injectDefaultsRecursive(field)
+// injectFieldDefaults injects the defaults of a single struct field.
+func injectFieldDefaults(field reflect.Value, structField reflect.StructField, path map[reflect.Type]bool) error {
+	if !structField.IsExported() {
+		return nil
+	}
+
+	// Handle nested struct or pointer to struct
+	switch field.Kind() {
+	case reflect.Struct:
+		err := injectDefaultsRecursive(field, path)
+		if err != nil {
+			return err
+		}
+	case reflect.Ptr:
+		if field.IsNil() && structField.Type.Elem().Kind() == reflect.Struct && !path[structField.Type.Elem()] {
+			field.Set(reflect.New(structField.Type.Elem()))
+		}
+		if !field.IsNil() && field.Elem().Kind() == reflect.Struct {
+			err := injectDefaultsRecursive(field.Elem(), path)
 			if err != nil {
 				return err
 			}
-		case reflect.Ptr:
-			if field.IsNil() && structField.Type.Elem().Kind() == reflect.Struct {
-				field.Set(reflect.New(structField.Type.Elem()))
-			}
-			if !field.IsNil() && field.Elem().Kind() == reflect.Struct {
-				err := injectDefaultsRecursive(field.Elem())
-				if err != nil {
-					return err
-				}
-			}
 		}
+	}
 
-		// Inject default value if empty
-		defaultVal := structField.Tag.Get("default")
-		if defaultVal != "" && isZeroValue(field) {
-			err := setFieldWithString(field, defaultVal)
-			if err != nil {
-				return err
-			}
+	// Inject default value if empty
+	defaultVal := structField.Tag.Get("default")
+	if defaultVal != "" && isZeroValue(field) {
+		err := setFieldWithString(field, defaultVal)
+		if err != nil {
+			return err
 		}
 	}
 	return nil
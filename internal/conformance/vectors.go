@@ -0,0 +1,214 @@
+// Package conformance declares the conformance vectors of the structo binary
+// format, maintained by cmd/structoconform. The types live outside of the
+// command so that their import path, part of the tagged schema fingerprint,
+// is the same in the command and its test.
+package conformance
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	structo "github.com/Lucifer07/Structo"
+)
+
+// The types below are the schemas of the conformance vectors. Other
+// implementations need their field order and kinds to read the blobs.
+
+type Scalars struct {
+	I   int
+	I8  int8
+	I16 int16
+	I32 int32
+	I64 int64
+	U   uint
+	U8  uint8
+	U16 uint16
+	U32 uint32
+	U64 uint64
+	F32 float32
+	F64 float64
+	B   bool
+	S   string
+}
+
+type Bytes struct {
+	Nil   []byte
+	Empty []byte
+	Data  []byte
+	Fixed [4]byte
+	Text  string
+}
+
+type Collections struct {
+	Ints   []int32
+	Nil    []string
+	Empty  []string
+	Fixed  [3]uint16
+	Nested [][]string
+	Counts map[string]int
+	NilMap map[int]bool
+}
+
+type Address struct {
+	City    string
+	ZipCode string
+}
+
+type Pointers struct {
+	Nil   *int
+	Count *int
+	Name  *string
+	Home  *Address
+}
+
+type Shared struct {
+	Home *Address
+	Work *Address
+}
+
+type Node struct {
+	Value int
+	Next  *Node
+}
+
+type Tagged struct {
+	ID      int64    `structo:"id=1"`
+	Name    string   `structo:"name"`
+	Email   string   `structo:",omitempty"`
+	Tags    []string `structo:",omitempty"`
+	Age     uint8
+	Secret  string `structo:"-"`
+	Address Address
+}
+
+type Private struct {
+	Public  string
+	private int
+}
+
+type Point struct {
+	X, Y int32
+}
+
+type Dynamic struct {
+	Values []interface{}
+	Fields map[string]interface{}
+	Shape  interface{}
+	None   interface{}
+}
+
+type WellKnown struct {
+	At      time.Time
+	Timeout time.Duration
+	Big     *big.Int
+	ID      uuid.UUID
+	IPv4    net.IP
+	IPv6    net.IP
+}
+
+// Level implements encoding.TextMarshaler, its text is embedded as a blob.
+type Level int
+
+func (l Level) MarshalText() ([]byte, error) {
+	return []byte(strings.Repeat("*", int(l))), nil
+}
+
+func (l *Level) UnmarshalText(text []byte) error {
+	if strings.Trim(string(text), "*") != "" {
+		return fmt.Errorf("invalid level %q", text)
+	}
+	*l = Level(len(text))
+	return nil
+}
+
+type Marshalers struct {
+	Level  Level
+	Levels []Level
+}
+
+func init() {
+	structo.Register("conform.point", Point{})
+}
+
+// Vector is a single conformance test case: Value encoded with Options.
+type Vector struct {
+	Name    string
+	Options []string
+	Value   interface{}
+	// Cyclic values cannot be written to the JSON index.
+	Cyclic bool
+}
+
+// Vectors returns all conformance vectors.
+func Vectors() []Vector {
+	scalars := Scalars{
+		I: -1, I8: math.MinInt8, I16: math.MaxInt16, I32: -70000, I64: math.MinInt64,
+		U: 1, U8: math.MaxUint8, U16: 300, U32: math.MaxUint32, U64: math.MaxUint64,
+		F32: 1.5, F64: -0.1, B: true, S: "structo",
+	}
+	bytes := Bytes{
+		Empty: []byte{},
+		Data:  []byte{0x00, 0x01, 0xfe, 0xff},
+		Fixed: [4]byte{'S', 'T', 'R', 'O'},
+		Text:  "héllo, 世界",
+	}
+	collections := Collections{
+		Ints:   []int32{1, -2, 300},
+		Empty:  []string{},
+		Fixed:  [3]uint16{1, 2, 3},
+		Nested: [][]string{{"a"}, nil, {"b", "c"}},
+		Counts: map[string]int{"b": 2, "a": 1, "ccc": 3},
+	}
+	count, name := 42, "Jane"
+	pointers := Pointers{
+		Count: &count,
+		Name:  &name,
+		Home:  &Address{City: "Jakarta", ZipCode: "10110"},
+	}
+	home := &Address{City: "Bandung", ZipCode: "40111"}
+	first := &Node{Value: 1}
+	first.Next = &Node{Value: 2, Next: first}
+	tagged := Tagged{ID: 7, Name: "Jane", Age: 30, Address: Address{City: "Jakarta"}}
+	dynamic := Dynamic{
+		Values: []interface{}{int64(1), "two", 3.5, true, nil, []byte{4}},
+		Fields: map[string]interface{}{"name": "Jane", "age": 30, "tags": []interface{}{"a", "b"}},
+		Shape:  Point{X: 1, Y: -1},
+	}
+	wellKnown := WellKnown{
+		At:      time.Date(2024, 2, 29, 13, 45, 30, 123456789, time.UTC),
+		Timeout: 90 * time.Second,
+		Big:     new(big.Int).Lsh(big.NewInt(-3), 100),
+		ID:      uuid.MustParse("6ba7b810-9dad-11d1-80b4-00c04fd430c8"),
+		IPv4:    net.IPv4(192, 168, 1, 1).To4(),
+		IPv6:    net.ParseIP("2001:db8::1"),
+	}
+	marshalers := Marshalers{Level: 3, Levels: []Level{1, 0, 2}}
+
+	return []Vector{
+		{Name: "scalars", Value: scalars},
+		{Name: "scalars_compact", Options: []string{"compact"}, Value: scalars},
+		{Name: "bytes", Value: bytes},
+		{Name: "bytes_compact", Options: []string{"compact"}, Value: bytes},
+		{Name: "collections", Options: []string{"canonical"}, Value: collections},
+		{Name: "collections_compact", Options: []string{"compact", "canonical"}, Value: collections},
+		{Name: "pointers", Value: pointers},
+		{Name: "pointers_shared", Value: Shared{Home: home, Work: home}},
+		{Name: "references_shared", Options: []string{"references"}, Value: Shared{Home: home, Work: home}},
+		{Name: "references_cycle", Options: []string{"references"}, Value: first, Cyclic: true},
+		{Name: "references_cycle_compact", Options: []string{"references", "compact"}, Value: first, Cyclic: true},
+		{Name: "tags", Value: tagged},
+		{Name: "tagged", Options: []string{"tagged"}, Value: tagged},
+		{Name: "tagged_compact", Options: []string{"tagged", "compact"}, Value: tagged},
+		{Name: "private", Value: Private{Public: "shown", private: 7}},
+		{Name: "exported_only", Options: []string{"exported"}, Value: Private{Public: "shown"}},
+		{Name: "interfaces", Options: []string{"canonical"}, Value: dynamic},
+		{Name: "well_known", Value: wellKnown},
+		{Name: "marshalers", Value: marshalers},
+	}
+}
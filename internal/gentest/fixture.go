@@ -0,0 +1,66 @@
+//go:generate go run github.com/Lucifer07/Structo/cmd/structogen -type=Fixture,Inner,Base
+
+// Package gentest holds a struct with structogen generated methods, used to
+// check that they stay in sync with the generator and encode exactly like
+// the reflective Converter.
+package gentest
+
+// Fixture has a field of every kind the generated code encodes inline and
+// some it falls back to structo.AppendField for.
+type Fixture struct {
+	Base
+	Flag     bool
+	Small    int8
+	Count    int
+	Port     uint16
+	Big      uint64
+	Ratio    float32
+	Score    float64
+	Name     string `structo:"name,id=3"`
+	Raw      []byte
+	Values   []int32
+	Tags     []string
+	Nil      []string
+	Inner    Inner
+	Ptr      *Inner
+	Attrs    map[string]int
+	Fixed    [2]uint8
+	Opt      string `structo:",omitempty"`
+	OptInner Inner  `structo:",omitempty"`
+	Skipped  string `structo:"-"`
+	note     string
+}
+
+type Inner struct {
+	Label string
+	Level uint8
+}
+
+type Base struct {
+	ID int64
+}
+
+// Sample returns a Fixture with every field set, except for the nil slice
+// and the zero omitempty Inner.
+func Sample() Fixture {
+	return Fixture{
+		Base:   Base{ID: -42},
+		Flag:   true,
+		Small:  -8,
+		Count:  1 << 40,
+		Port:   8080,
+		Big:    1<<64 - 1,
+		Ratio:  1.5,
+		Score:  -0.25,
+		Name:   "héllo",
+		Raw:    []byte{0, 1, 0xff},
+		Values: []int32{-1, 0, 70000},
+		Tags:   []string{"a", ""},
+		Inner:  Inner{Label: "in", Level: 3},
+		Ptr:    &Inner{Label: "ptr"},
+		Attrs:  map[string]int{"x": 1},
+		Fixed:  [2]uint8{4, 5},
+		Opt:    "set",
+		note:   "unexported",
+	}
+}
@@ -0,0 +1,417 @@
+// Code generated by structogen. DO NOT EDIT.
+
+package gentest
+
+import (
+	"encoding/binary"
+	"io"
+	"math"
+	"reflect"
+
+	structo "github.com/Lucifer07/Structo"
+	"github.com/Lucifer07/Structo/errdefs"
+)
+
+// StructoFormatVersion reports the binary format the methods of Fixture were generated for.
+func (*Fixture) StructoFormatVersion() int { return 1 }
+
+// MarshalStructo appends the binary encoding of x to b.
+func (x *Fixture) MarshalStructo(b []byte) ([]byte, error) {
+	var err error
+	if b, err = x.Base.MarshalStructo(b); err != nil {
+		return nil, err
+	}
+	if x.Flag {
+		b = append(b, 1)
+	} else {
+		b = append(b, 0)
+	}
+	b = binary.LittleEndian.AppendUint64(b, uint64(int64(x.Small)))
+	b = binary.LittleEndian.AppendUint64(b, uint64(int64(x.Count)))
+	b = binary.LittleEndian.AppendUint64(b, uint64(x.Port))
+	b = binary.LittleEndian.AppendUint64(b, uint64(x.Big))
+	b = binary.LittleEndian.AppendUint64(b, math.Float64bits(float64(x.Ratio)))
+	b = binary.LittleEndian.AppendUint64(b, math.Float64bits(float64(x.Score)))
+	b = binary.LittleEndian.AppendUint32(b, uint32(len(x.Name)))
+	b = append(b, x.Name...)
+	if x.Raw == nil {
+		b = binary.LittleEndian.AppendUint32(b, math.MaxUint32)
+	} else {
+		b = binary.LittleEndian.AppendUint32(b, uint32(len(x.Raw)))
+		b = append(b, x.Raw...)
+	}
+	if x.Values == nil {
+		b = binary.LittleEndian.AppendUint32(b, math.MaxUint32)
+	} else {
+		b = binary.LittleEndian.AppendUint32(b, uint32(len(x.Values)))
+		for i := range x.Values {
+			b = binary.LittleEndian.AppendUint64(b, uint64(int64(x.Values[i])))
+		}
+	}
+	if x.Tags == nil {
+		b = binary.LittleEndian.AppendUint32(b, math.MaxUint32)
+	} else {
+		b = binary.LittleEndian.AppendUint32(b, uint32(len(x.Tags)))
+		for i := range x.Tags {
+			b = binary.LittleEndian.AppendUint32(b, uint32(len(x.Tags[i])))
+			b = append(b, x.Tags[i]...)
+		}
+	}
+	if x.Nil == nil {
+		b = binary.LittleEndian.AppendUint32(b, math.MaxUint32)
+	} else {
+		b = binary.LittleEndian.AppendUint32(b, uint32(len(x.Nil)))
+		for i := range x.Nil {
+			b = binary.LittleEndian.AppendUint32(b, uint32(len(x.Nil[i])))
+			b = append(b, x.Nil[i]...)
+		}
+	}
+	if b, err = x.Inner.MarshalStructo(b); err != nil {
+		return nil, err
+	}
+	if b, err = structo.AppendField(b, &x.Ptr); err != nil {
+		return nil, err
+	}
+	if b, err = structo.AppendField(b, &x.Attrs); err != nil {
+		return nil, err
+	}
+	if b, err = structo.AppendField(b, &x.Fixed); err != nil {
+		return nil, err
+	}
+	if x.Opt == "" {
+		b = append(b, 0)
+	} else {
+		b = append(b, 1)
+		b = binary.LittleEndian.AppendUint32(b, uint32(len(x.Opt)))
+		b = append(b, x.Opt...)
+	}
+	if reflect.ValueOf(&x.OptInner).Elem().IsZero() {
+		b = append(b, 0)
+	} else {
+		b = append(b, 1)
+		if b, err = x.OptInner.MarshalStructo(b); err != nil {
+			return nil, err
+		}
+	}
+	b = binary.LittleEndian.AppendUint32(b, uint32(len(x.note)))
+	b = append(b, x.note...)
+	return b, nil
+}
+
+// UnmarshalStructo decodes x from the start of data and returns the number of bytes read.
+func (x *Fixture) UnmarshalStructo(data []byte) (int, error) {
+	var n int
+	{
+		m, err := x.Base.UnmarshalStructo(data[n:])
+		if err != nil {
+			return 0, err
+		}
+		n += m
+	}
+	if len(data)-n < 1 {
+		return 0, io.ErrUnexpectedEOF
+	}
+	x.Flag = data[n] == 1
+	n++
+	if len(data)-n < 8 {
+		return 0, io.ErrUnexpectedEOF
+	}
+	x.Small = int8(int64(binary.LittleEndian.Uint64(data[n:])))
+	n += 8
+	if len(data)-n < 8 {
+		return 0, io.ErrUnexpectedEOF
+	}
+	x.Count = int(int64(binary.LittleEndian.Uint64(data[n:])))
+	n += 8
+	if len(data)-n < 8 {
+		return 0, io.ErrUnexpectedEOF
+	}
+	x.Port = uint16(binary.LittleEndian.Uint64(data[n:]))
+	n += 8
+	if len(data)-n < 8 {
+		return 0, io.ErrUnexpectedEOF
+	}
+	x.Big = uint64(binary.LittleEndian.Uint64(data[n:]))
+	n += 8
+	if len(data)-n < 8 {
+		return 0, io.ErrUnexpectedEOF
+	}
+	x.Ratio = float32(math.Float64frombits(binary.LittleEndian.Uint64(data[n:])))
+	n += 8
+	if len(data)-n < 8 {
+		return 0, io.ErrUnexpectedEOF
+	}
+	x.Score = float64(math.Float64frombits(binary.LittleEndian.Uint64(data[n:])))
+	n += 8
+	{
+		if len(data)-n < 4 {
+			return 0, io.ErrUnexpectedEOF
+		}
+		l := int(int32(binary.LittleEndian.Uint32(data[n:])))
+		n += 4
+		if l < 0 {
+			return 0, errdefs.ErrInvalidLength
+		}
+		if len(data)-n < l {
+			return 0, io.ErrUnexpectedEOF
+		}
+		x.Name = string(data[n : n+l])
+		n += l
+	}
+	{
+		if len(data)-n < 4 {
+			return 0, io.ErrUnexpectedEOF
+		}
+		l := int(int32(binary.LittleEndian.Uint32(data[n:])))
+		n += 4
+		switch {
+		case l == -1:
+			x.Raw = nil
+		case l < 0:
+			return 0, errdefs.ErrInvalidLength
+		case l > len(data)-n:
+			return 0, io.ErrUnexpectedEOF
+		default:
+			x.Raw = make([]byte, l)
+			copy(x.Raw, data[n:])
+			n += l
+		}
+	}
+	{
+		if len(data)-n < 4 {
+			return 0, io.ErrUnexpectedEOF
+		}
+		l := int(int32(binary.LittleEndian.Uint32(data[n:])))
+		n += 4
+		switch {
+		case l == -1:
+			x.Values = nil
+		case l < 0:
+			return 0, errdefs.ErrInvalidLength
+		case l > len(data)-n:
+			return 0, io.ErrUnexpectedEOF
+		default:
+			x.Values = make([]int32, l)
+			for i := range x.Values {
+				if len(data)-n < 8 {
+					return 0, io.ErrUnexpectedEOF
+				}
+				x.Values[i] = int32(int64(binary.LittleEndian.Uint64(data[n:])))
+				n += 8
+			}
+		}
+	}
+	{
+		if len(data)-n < 4 {
+			return 0, io.ErrUnexpectedEOF
+		}
+		l := int(int32(binary.LittleEndian.Uint32(data[n:])))
+		n += 4
+		switch {
+		case l == -1:
+			x.Tags = nil
+		case l < 0:
+			return 0, errdefs.ErrInvalidLength
+		case l > len(data)-n:
+			return 0, io.ErrUnexpectedEOF
+		default:
+			x.Tags = make([]string, l)
+			for i := range x.Tags {
+				{
+					if len(data)-n < 4 {
+						return 0, io.ErrUnexpectedEOF
+					}
+					l := int(int32(binary.LittleEndian.Uint32(data[n:])))
+					n += 4
+					if l < 0 {
+						return 0, errdefs.ErrInvalidLength
+					}
+					if len(data)-n < l {
+						return 0, io.ErrUnexpectedEOF
+					}
+					x.Tags[i] = string(data[n : n+l])
+					n += l
+				}
+			}
+		}
+	}
+	{
+		if len(data)-n < 4 {
+			return 0, io.ErrUnexpectedEOF
+		}
+		l := int(int32(binary.LittleEndian.Uint32(data[n:])))
+		n += 4
+		switch {
+		case l == -1:
+			x.Nil = nil
+		case l < 0:
+			return 0, errdefs.ErrInvalidLength
+		case l > len(data)-n:
+			return 0, io.ErrUnexpectedEOF
+		default:
+			x.Nil = make([]string, l)
+			for i := range x.Nil {
+				{
+					if len(data)-n < 4 {
+						return 0, io.ErrUnexpectedEOF
+					}
+					l := int(int32(binary.LittleEndian.Uint32(data[n:])))
+					n += 4
+					if l < 0 {
+						return 0, errdefs.ErrInvalidLength
+					}
+					if len(data)-n < l {
+						return 0, io.ErrUnexpectedEOF
+					}
+					x.Nil[i] = string(data[n : n+l])
+					n += l
+				}
+			}
+		}
+	}
+	{
+		m, err := x.Inner.UnmarshalStructo(data[n:])
+		if err != nil {
+			return 0, err
+		}
+		n += m
+	}
+	{
+		m, err := structo.ReadField(data[n:], &x.Ptr)
+		if err != nil {
+			return 0, err
+		}
+		n += m
+	}
+	{
+		m, err := structo.ReadField(data[n:], &x.Attrs)
+		if err != nil {
+			return 0, err
+		}
+		n += m
+	}
+	{
+		m, err := structo.ReadField(data[n:], &x.Fixed)
+		if err != nil {
+			return 0, err
+		}
+		n += m
+	}
+	if len(data)-n < 1 {
+		return 0, io.ErrUnexpectedEOF
+	}
+	if data[n] > 1 {
+		return 0, errdefs.ErrInvalidTypeData
+	}
+	n++
+	if data[n-1] == 0 {
+		x.Opt = ""
+	} else {
+		{
+			if len(data)-n < 4 {
+				return 0, io.ErrUnexpectedEOF
+			}
+			l := int(int32(binary.LittleEndian.Uint32(data[n:])))
+			n += 4
+			if l < 0 {
+				return 0, errdefs.ErrInvalidLength
+			}
+			if len(data)-n < l {
+				return 0, io.ErrUnexpectedEOF
+			}
+			x.Opt = string(data[n : n+l])
+			n += l
+		}
+	}
+	if len(data)-n < 1 {
+		return 0, io.ErrUnexpectedEOF
+	}
+	if data[n] > 1 {
+		return 0, errdefs.ErrInvalidTypeData
+	}
+	n++
+	if data[n-1] == 0 {
+		reflect.ValueOf(&x.OptInner).Elem().SetZero()
+	} else {
+		{
+			m, err := x.OptInner.UnmarshalStructo(data[n:])
+			if err != nil {
+				return 0, err
+			}
+			n += m
+		}
+	}
+	{
+		if len(data)-n < 4 {
+			return 0, io.ErrUnexpectedEOF
+		}
+		l := int(int32(binary.LittleEndian.Uint32(data[n:])))
+		n += 4
+		if l < 0 {
+			return 0, errdefs.ErrInvalidLength
+		}
+		if len(data)-n < l {
+			return 0, io.ErrUnexpectedEOF
+		}
+		x.note = string(data[n : n+l])
+		n += l
+	}
+	return n, nil
+}
+
+// StructoFormatVersion reports the binary format the methods of Inner were generated for.
+func (*Inner) StructoFormatVersion() int { return 1 }
+
+// MarshalStructo appends the binary encoding of x to b.
+func (x *Inner) MarshalStructo(b []byte) ([]byte, error) {
+	b = binary.LittleEndian.AppendUint32(b, uint32(len(x.Label)))
+	b = append(b, x.Label...)
+	b = binary.LittleEndian.AppendUint64(b, uint64(x.Level))
+	return b, nil
+}
+
+// UnmarshalStructo decodes x from the start of data and returns the number of bytes read.
+func (x *Inner) UnmarshalStructo(data []byte) (int, error) {
+	var n int
+	{
+		if len(data)-n < 4 {
+			return 0, io.ErrUnexpectedEOF
+		}
+		l := int(int32(binary.LittleEndian.Uint32(data[n:])))
+		n += 4
+		if l < 0 {
+			return 0, errdefs.ErrInvalidLength
+		}
+		if len(data)-n < l {
+			return 0, io.ErrUnexpectedEOF
+		}
+		x.Label = string(data[n : n+l])
+		n += l
+	}
+	if len(data)-n < 8 {
+		return 0, io.ErrUnexpectedEOF
+	}
+	x.Level = uint8(binary.LittleEndian.Uint64(data[n:]))
+	n += 8
+	return n, nil
+}
+
+// StructoFormatVersion reports the binary format the methods of Base were generated for.
+func (*Base) StructoFormatVersion() int { return 1 }
+
+// MarshalStructo appends the binary encoding of x to b.
+func (x *Base) MarshalStructo(b []byte) ([]byte, error) {
+	b = binary.LittleEndian.AppendUint64(b, uint64(int64(x.ID)))
+	return b, nil
+}
+
+// UnmarshalStructo decodes x from the start of data and returns the number of bytes read.
+func (x *Base) UnmarshalStructo(data []byte) (int, error) {
+	var n int
+	if len(data)-n < 8 {
+		return 0, io.ErrUnexpectedEOF
+	}
+	x.ID = int64(int64(binary.LittleEndian.Uint64(data[n:])))
+	n += 8
+	return n, nil
+}